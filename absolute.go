@@ -0,0 +1,44 @@
+package static
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+var errNoBaseURL = errors.New("static: no base URL for absolute URL")
+
+// AbsoluteURLResolver is a Resolver which also provides fully qualified URLs.
+type AbsoluteURLResolver interface {
+	Resolver
+	AbsoluteURLContext(ctx context.Context, names ...string) (string, error)
+}
+
+var _ AbsoluteURLResolver = (*Handler)(nil)
+
+// AbsoluteURLContext returns the hashed URL for the given names prefixed with
+// the BaseURL. It fails if no BaseURL is configured.
+func (h *Handler) AbsoluteURLContext(ctx context.Context, names ...string) (string, error) {
+	if h.BaseURL == "" {
+		return "", errNoBaseURL
+	}
+	u, err := h.URLContext(ctx, names...)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(h.BaseURL, "/") + u, nil
+}
+
+// AbsoluteURL returns the fully qualified hashed URL for the given names using
+// the Resolver in the context.
+func AbsoluteURL(ctx context.Context, names ...string) (string, error) {
+	r := FromContext(ctx)
+	if r == nil {
+		return "", errNoHandlerInContext
+	}
+	ar, ok := r.(AbsoluteURLResolver)
+	if !ok {
+		return "", errNoBaseURL
+	}
+	return ar.AbsoluteURLContext(ctx, names...)
+}
@@ -0,0 +1,39 @@
+package static
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestAbsoluteURL(t *testing.T) {
+	h := &Handler{
+		Path:    "/static/",
+		BaseURL: "https://cdn.example.com/",
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	}
+	v, err := AbsoluteURL(makeCtx(h), "foo.png")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, "https://cdn.example.com/static/W1siZm9vLnBuZyIsImFjYmQxOGRiIl1d.png")
+}
+
+func TestAbsoluteURLNoBase(t *testing.T) {
+	h := &Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			panic("not reached")
+		}),
+	}
+	_, err := AbsoluteURL(makeCtx(h), "foo.png")
+	ensure.DeepEqual(t, err, errNoBaseURL)
+}
+
+func TestAbsoluteURLUnsupportedResolver(t *testing.T) {
+	_, err := AbsoluteURL(NewContext(context.Background(), urlResolver{}), "foo.png")
+	ensure.DeepEqual(t, err, errNoBaseURL)
+	_, err = AbsoluteURL(context.Background(), "foo.png")
+	ensure.DeepEqual(t, err, errNoHandlerInContext)
+}
@@ -0,0 +1,79 @@
+package static
+
+import (
+	"net/http"
+	"time"
+)
+
+// Access describes a request served by a Handler.
+type Access struct {
+	Method   string
+	Path     string
+	Status   int
+	Bytes    int64 // Bytes of the response body written.
+	Duration time.Duration
+}
+
+// accessWriter records the status and size of a response.
+type accessWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *accessWriter) WriteHeader(status int) {
+	if w.status == 0 && status >= http.StatusOK {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *accessWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Flush sends any buffered data to the client, if the underlying
+// ResponseWriter supports it.
+func (w *accessWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		f.Flush()
+	}
+}
+
+// Push initiates an HTTP/2 server push, if the underlying ResponseWriter
+// supports it.
+func (w *accessWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// Unwrap returns the underlying ResponseWriter, for http.ResponseController.
+func (w *accessWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (h *Handler) serveLogged(w http.ResponseWriter, r *http.Request, serve http.HandlerFunc) {
+	start := time.Now()
+	aw := &accessWriter{ResponseWriter: w}
+	serve(aw, r)
+	if aw.status == 0 {
+		aw.status = http.StatusOK
+	}
+	h.AccessLog(Access{
+		Method:   r.Method,
+		Path:     r.URL.Path,
+		Status:   aw.status,
+		Bytes:    aw.bytes,
+		Duration: time.Since(start),
+	})
+}
@@ -0,0 +1,53 @@
+package static
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestAccessLog(t *testing.T) {
+	var entries []Access
+	h := &Handler{
+		Path: "/",
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+		AccessLog: func(a Access) {
+			entries = append(entries, a)
+		},
+	}
+	v, err := h.URL("a.css")
+	ensure.Nil(t, err)
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", v, nil))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("HEAD", "/W1siZm9vIiwiYmFyIl1d", nil))
+
+	ensure.DeepEqual(t, len(entries), 2)
+	ensure.DeepEqual(t, entries[0].Method, "GET")
+	ensure.DeepEqual(t, entries[0].Path, v)
+	ensure.DeepEqual(t, entries[0].Status, http.StatusOK)
+	ensure.DeepEqual(t, entries[0].Bytes, int64(3))
+	ensure.True(t, entries[0].Duration >= 0)
+	ensure.DeepEqual(t, entries[1].Method, "HEAD")
+	ensure.DeepEqual(t, entries[1].Status, http.StatusNotFound)
+}
+
+func TestAccessWriterInformational(t *testing.T) {
+	w := &accessWriter{ResponseWriter: httptest.NewRecorder()}
+	w.WriteHeader(http.StatusEarlyHints)
+	w.WriteHeader(http.StatusNotModified)
+	ensure.DeepEqual(t, w.status, http.StatusNotModified)
+}
+
+func TestAccessWriterPassthrough(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := &accessWriter{ResponseWriter: rec}
+	w.Flush()
+	ensure.True(t, rec.Flushed)
+	ensure.DeepEqual(t, w.status, http.StatusOK)
+	ensure.DeepEqual(t, w.Push("/a.css", nil), http.ErrNotSupported)
+	ensure.DeepEqual(t, w.Unwrap(), http.ResponseWriter(rec))
+	ensure.Nil(t, http.NewResponseController(w).Flush())
+}
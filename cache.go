@@ -0,0 +1,173 @@
+package static
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type cacheEntry struct {
+	file file
+	used time.Time
+}
+
+// fileCache is a LRU cache of loaded files, bounded by the total size of their
+// contents and optionally by how long they have gone unused. The zero value
+// is an empty unbounded cache.
+type fileCache struct {
+	mu        sync.Mutex
+	size      int64
+	evictions int64
+	ll        *list.List
+	items     map[string]*list.Element
+	now       func() time.Time // for tests
+}
+
+func (c *fileCache) clock() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+	return time.Now()
+}
+
+func expired(e *cacheEntry, now time.Time, ttl time.Duration) bool {
+	return ttl > 0 && now.Sub(e.used) > ttl
+}
+
+func (c *fileCache) removeElement(e *list.Element) file {
+	entry := e.Value.(*cacheEntry)
+	c.ll.Remove(e)
+	delete(c.items, entry.file.Name)
+	c.size -= entry.file.size()
+	return entry.file
+}
+
+// get returns the cached file, unless it has gone unused for longer than ttl.
+// A ttl of zero or less means files never expire. Expired files are dropped
+// from the least recently used end on each call, so their memory is reclaimed
+// even if nothing more is added.
+func (c *fileCache) get(name string, ttl time.Duration) (file, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.clock()
+	c.expire(now, ttl)
+	e, found := c.items[name]
+	if !found {
+		return file{}, false
+	}
+	entry := e.Value.(*cacheEntry)
+	entry.used = now
+	c.ll.MoveToFront(e)
+	return entry.file, true
+}
+
+// expire drops the files unused for longer than ttl, which are all at the
+// back of the list.
+func (c *fileCache) expire(now time.Time, ttl time.Duration) {
+	if ttl <= 0 || c.ll == nil {
+		return
+	}
+	for e := c.ll.Back(); e != nil && expired(e.Value.(*cacheEntry), now, ttl); e = c.ll.Back() {
+		c.removeElement(e)
+		c.evictions++
+	}
+}
+
+// add caches the file, and returns the files evicted to keep the total size
+// within maxBytes along with those unused for longer than ttl. Zero or less
+// for either means no limit. The most recently added file is never evicted.
+func (c *fileCache) add(f file, maxBytes int64, ttl time.Duration) []file {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.items == nil {
+		c.ll = list.New()
+		c.items = make(map[string]*list.Element)
+	}
+	if e, found := c.items[f.Name]; found {
+		c.removeElement(e)
+	}
+	now := c.clock()
+	c.items[f.Name] = c.ll.PushFront(&cacheEntry{file: f, used: now})
+	c.size += f.size()
+
+	var evicted []file
+	for c.ll.Len() > 1 {
+		e := c.ll.Back()
+		overSize := maxBytes > 0 && c.size > maxBytes
+		if !overSize && !expired(e.Value.(*cacheEntry), now, ttl) {
+			break
+		}
+		evicted = append(evicted, c.removeElement(e))
+		c.evictions++
+	}
+	return evicted
+}
+
+func (c *fileCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ll = nil
+	c.items = nil
+	c.size = 0
+}
+
+// files returns all the cached files.
+func (c *fileCache) files() []file {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ll == nil {
+		return nil
+	}
+	files := make([]file, 0, len(c.items))
+	for e := c.ll.Front(); e != nil; e = e.Next() {
+		files = append(files, e.Value.(*cacheEntry).file)
+	}
+	return files
+}
+
+// stats returns the number of evictions, entries and bytes held.
+func (c *fileCache) stats() (int64, int, int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.evictions, len(c.items), c.size
+}
+
+func (c *fileCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.items)
+}
+
+// Stats describes the file cache of a Handler.
+type Stats struct {
+	Hits      int64 // Files found in the cache.
+	Misses    int64 // Files which had to be loaded from the Box.
+	Evictions int64 // Files dropped due to CacheSize or CacheTTL.
+	Entries   int   // Files currently cached.
+	Bytes     int64 // Total size of the cached contents.
+}
+
+// Stats returns statistics about the file cache, to help size it and detect
+// leaks.
+func (h *Handler) Stats() Stats {
+	evictions, entries, size := h.cache().stats()
+	return Stats{
+		Hits:      h.hits.Load(),
+		Misses:    h.misses.Load(),
+		Evictions: evictions,
+		Entries:   entries,
+		Bytes:     size,
+	}
+}
+
+// remove drops the named file, and reports if it was cached.
+func (c *fileCache) remove(name string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, found := c.items[name]
+	if !found {
+		return false
+	}
+	c.removeElement(e)
+	return true
+}
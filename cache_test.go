@@ -0,0 +1,140 @@
+package static
+
+import (
+	"testing"
+	"time"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestFileCacheGetMissing(t *testing.T) {
+	var c fileCache
+	_, found := c.get("a", 0)
+	ensure.False(t, found)
+}
+
+func TestFileCacheUnbounded(t *testing.T) {
+	var c fileCache
+	ensure.True(t, c.add(file{Name: "a", Content: []byte("aaa")}, 0, 0) == nil)
+	ensure.True(t, c.add(file{Name: "b", Content: []byte("bbb")}, 0, 0) == nil)
+	ensure.DeepEqual(t, c.len(), 2)
+	ensure.DeepEqual(t, c.size, int64(6))
+}
+
+func TestFileCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	var c fileCache
+	c.add(file{Name: "a", Content: []byte("aa")}, 4, 0)
+	c.add(file{Name: "b", Content: []byte("bb")}, 4, 0)
+	_, found := c.get("a", 0)
+	ensure.True(t, found)
+	evicted := c.add(file{Name: "c", Content: []byte("cc")}, 4, 0)
+	ensure.DeepEqual(t, evicted, []file{{Name: "b", Content: []byte("bb")}})
+	_, found = c.get("b", 0)
+	ensure.False(t, found)
+	ensure.DeepEqual(t, c.len(), 2)
+	ensure.DeepEqual(t, c.size, int64(4))
+}
+
+func TestFileCacheKeepsOversized(t *testing.T) {
+	var c fileCache
+	c.add(file{Name: "a", Content: []byte("a")}, 2, 0)
+	evicted := c.add(file{Name: "b", Content: []byte("bbb")}, 2, 0)
+	ensure.DeepEqual(t, len(evicted), 1)
+	f, found := c.get("b", 0)
+	ensure.True(t, found)
+	ensure.DeepEqual(t, f.Name, "b")
+}
+
+func TestFileCacheReplace(t *testing.T) {
+	var c fileCache
+	c.add(file{Name: "a", Content: []byte("aaa")}, 0, 0)
+	c.add(file{Name: "a", Content: []byte("a")}, 0, 0)
+	ensure.DeepEqual(t, c.len(), 1)
+	ensure.DeepEqual(t, c.size, int64(1))
+}
+
+func TestFileCacheClear(t *testing.T) {
+	var c fileCache
+	c.add(file{Name: "a", Content: []byte("aaa")}, 0, 0)
+	c.clear()
+	ensure.DeepEqual(t, c.len(), 0)
+	ensure.DeepEqual(t, c.size, int64(0))
+}
+
+func TestFileCacheTTL(t *testing.T) {
+	now := time.Unix(0, 0)
+	c := fileCache{now: func() time.Time { return now }}
+	c.add(file{Name: "a"}, 0, time.Minute)
+	c.add(file{Name: "b"}, 0, time.Minute)
+
+	now = now.Add(45 * time.Second)
+	_, found := c.get("a", time.Minute)
+	ensure.True(t, found)
+
+	now = now.Add(30 * time.Second)
+	_, found = c.get("b", time.Minute)
+	ensure.False(t, found)
+	_, found = c.get("a", time.Minute)
+	ensure.True(t, found)
+	ensure.DeepEqual(t, c.len(), 1)
+}
+
+func TestFileCacheTTLSweepsOnAdd(t *testing.T) {
+	now := time.Unix(0, 0)
+	c := fileCache{now: func() time.Time { return now }}
+	c.add(file{Name: "a"}, 0, time.Minute)
+	now = now.Add(2 * time.Minute)
+	evicted := c.add(file{Name: "b"}, 0, time.Minute)
+	ensure.DeepEqual(t, evicted, []file{{Name: "a"}})
+	ensure.DeepEqual(t, c.len(), 1)
+}
+
+func TestFileCacheTTLSweepsOnGet(t *testing.T) {
+	now := time.Unix(0, 0)
+	c := fileCache{now: func() time.Time { return now }}
+	c.add(file{Name: "a", Content: []byte("foo")}, 0, time.Minute)
+	c.add(file{Name: "b", Content: []byte("bar")}, 0, time.Minute)
+	now = now.Add(2 * time.Minute)
+	_, found := c.get("missing", time.Minute)
+	ensure.False(t, found)
+	evictions, entries, size := c.stats()
+	ensure.DeepEqual(t, evictions, int64(2))
+	ensure.DeepEqual(t, entries, 0)
+	ensure.DeepEqual(t, size, int64(0))
+}
+
+func TestFileCacheStats(t *testing.T) {
+	now := time.Unix(0, 0)
+	c := fileCache{now: func() time.Time { return now }}
+	c.add(file{Name: "a", Content: []byte("aa")}, 4, time.Minute)
+	c.add(file{Name: "b", Content: []byte("bb")}, 4, time.Minute)
+	c.add(file{Name: "c", Content: []byte("cc")}, 4, time.Minute)
+	now = now.Add(2 * time.Minute)
+	c.get("c", time.Minute)
+	evictions, entries, size := c.stats()
+	ensure.DeepEqual(t, evictions, int64(3))
+	ensure.DeepEqual(t, entries, 0)
+	ensure.DeepEqual(t, size, int64(0))
+}
+
+func TestStats(t *testing.T) {
+	h := &Handler{
+		CacheSize: 4,
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	}
+	_, err := h.URL("a")
+	ensure.Nil(t, err)
+	_, err = h.URL("a")
+	ensure.Nil(t, err)
+	_, err = h.URL("b")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, h.Stats(), Stats{
+		Hits:      1,
+		Misses:    2,
+		Evictions: 1,
+		Entries:   1,
+		Bytes:     3,
+	})
+}
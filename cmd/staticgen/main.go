@@ -0,0 +1,87 @@
+// Command staticgen writes hashed copies of all the files in a directory,
+// along with a manifest of their URLs, for deployments where the files are
+// served directly by a CDN. The output directory should be served at the
+// given path.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/daaku/go.static"
+)
+
+func run(dir, out, urlPath string, manifestOnly bool) error {
+	h := &static.Handler{
+		Path: urlPath,
+		Box:  static.FSBox(os.DirFS(dir)),
+	}
+	defer h.Close()
+
+	// the output directory may be inside the input directory, in which case
+	// its files must not be hashed again.
+	skip, err := filepath.Rel(dir, out)
+	if err != nil {
+		return err
+	}
+	skip = filepath.ToSlash(skip)
+
+	err = fs.WalkDir(os.DirFS(dir), ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if name == skip {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		u, err := h.URL(name)
+		if err != nil {
+			return err
+		}
+		if manifestOnly {
+			return nil
+		}
+		contents, err := h.ContentContext(context.Background(), name)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(out, path.Base(u)), contents, 0644)
+	})
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(filepath.Join(out, "manifest.json"))
+	if err != nil {
+		return err
+	}
+	if err := h.WriteManifest(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func main() {
+	dir := flag.String("dir", ".", "directory of files to hash")
+	out := flag.String("out", "out", "output directory")
+	urlPath := flag.String("path", "/static/", "path the output directory is served at")
+	manifestOnly := flag.Bool("manifest-only", false, "only write the manifest")
+	flag.Parse()
+
+	if err := os.MkdirAll(*out, 0755); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := run(*dir, *out, *urlPath, *manifestOnly); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/daaku/go.static"
+	"github.com/facebookgo/ensure"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	out := t.TempDir()
+	ensure.Nil(t, os.MkdirAll(filepath.Join(dir, "css"), 0755))
+	ensure.Nil(t, os.WriteFile(filepath.Join(dir, "css", "app.css"), []byte("foo"), 0644))
+	ensure.Nil(t, run(dir, out, "/static/", false))
+
+	contents, err := os.ReadFile(filepath.Join(out, "W1siY3NzL2FwcC5jc3MiLCJhY2JkMThkYiJdXQ.css"))
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, string(contents), "foo")
+
+	manifest, err := os.ReadFile(filepath.Join(out, "manifest.json"))
+	ensure.Nil(t, err)
+	var h static.Handler
+	ensure.Nil(t, h.LoadManifest(bytes.NewReader(manifest)))
+	u, err := h.URL("css/app.css")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, u, "/static/W1siY3NzL2FwcC5jc3MiLCJhY2JkMThkYiJdXQ.css")
+}
+
+func TestRunManifestOnly(t *testing.T) {
+	dir := t.TempDir()
+	out := t.TempDir()
+	ensure.Nil(t, os.WriteFile(filepath.Join(dir, "app.css"), []byte("foo"), 0644))
+	ensure.Nil(t, run(dir, out, "/static/", true))
+	entries, err := os.ReadDir(out)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, len(entries), 1)
+	ensure.DeepEqual(t, entries[0].Name(), "manifest.json")
+}
+
+func TestRunOutInsideDir(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	ensure.Nil(t, os.MkdirAll(out, 0755))
+	ensure.Nil(t, os.WriteFile(filepath.Join(dir, "app.css"), []byte("foo"), 0644))
+	ensure.Nil(t, os.WriteFile(filepath.Join(out, "old.css"), []byte("old"), 0644))
+	ensure.Nil(t, run(dir, out, "/static/", false))
+
+	manifest, err := os.ReadFile(filepath.Join(out, "manifest.json"))
+	ensure.Nil(t, err)
+	var entries []static.ManifestEntry
+	ensure.Nil(t, json.Unmarshal(manifest, &entries))
+	ensure.DeepEqual(t, len(entries), 1)
+	ensure.DeepEqual(t, entries[0].Names, []string{"app.css"})
+}
@@ -0,0 +1,84 @@
+package static
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"os/exec"
+	"path"
+	"strings"
+)
+
+// Compiler compiles source files, such as TypeScript or SASS, into the script
+// or stylesheet served for them.
+type Compiler interface {
+	Compile(name string, in []byte) ([]byte, error)
+
+	// ContentType returns the Content-Type of the compiled output, such as
+	// "text/javascript; charset=utf-8".
+	ContentType() string
+}
+
+// outputExt returns the lower case extension of the file as served, which is
+// ".js" or ".css" for compiled scripts and stylesheets.
+func (h *Handler) outputExt(name string) string {
+	ext := strings.ToLower(path.Ext(name))
+	if c, found := h.Compilers[ext]; found {
+		mediatype, _, _ := mime.ParseMediaType(c.ContentType())
+		switch mediatype {
+		case "text/javascript", "application/javascript":
+			return ".js"
+		case "text/css":
+			return ".css"
+		}
+	}
+	return ext
+}
+
+// CommandCompiler compiles files by piping them through an external command,
+// which reads the source on stdin and writes the output to stdout.
+type CommandCompiler struct {
+	Command []string
+	Type    string // Content-Type of the output.
+}
+
+// Compile runs the command with the contents as its stdin. Failures include
+// the output on stderr.
+func (c *CommandCompiler) Compile(name string, in []byte) ([]byte, error) {
+	if len(c.Command) == 0 {
+		return nil, fmt.Errorf("static: no command to compile %s", name)
+	}
+	cmd := exec.Command(c.Command[0], c.Command[1:]...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdin = bytes.NewReader(in)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
+
+// ContentType returns the Type.
+func (c *CommandCompiler) ContentType() string {
+	return c.Type
+}
+
+// Esbuild returns a CommandCompiler running the esbuild binary with the given
+// loader, such as "ts" or "tsx", to compile TypeScript and modern JavaScript
+// into ES modules. The args are passed to esbuild, such as "--target=es2017".
+func Esbuild(loader string, args ...string) *CommandCompiler {
+	command := append([]string{"esbuild", "--loader=" + loader, "--format=esm"}, args...)
+	return &CommandCompiler{Command: command, Type: webTypes[".js"]}
+}
+
+// Sass returns a CommandCompiler running the dart-sass binary to compile SCSS
+// into CSS. The args are passed to sass, such as "--load-path=static/scss" to
+// resolve @use rules, or "--indented" for the indented ".sass" syntax.
+func Sass(args ...string) *CommandCompiler {
+	command := append([]string{"sass", "--stdin", "--no-source-map"}, args...)
+	return &CommandCompiler{Command: command, Type: webTypes[".css"]}
+}
@@ -0,0 +1,118 @@
+package static
+
+import (
+	"bytes"
+	"os/exec"
+	"regexp"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+)
+
+type stripTypes struct{}
+
+func (stripTypes) Compile(name string, in []byte) ([]byte, error) {
+	return bytes.ReplaceAll(in, []byte(": number"), nil), nil
+}
+
+func (stripTypes) ContentType() string {
+	return "text/javascript; charset=utf-8"
+}
+
+func TestCompiler(t *testing.T) {
+	h := New(
+		WithPath("/"),
+		WithBox(funcBox(func(name string) ([]byte, error) {
+			return []byte("let a: number = 1 "), nil
+		})),
+		WithCompiler(".TS", stripTypes{}),
+		WithTransform(".js", func(name string, in []byte) ([]byte, error) {
+			return bytes.TrimSpace(in), nil
+		}),
+	)
+	w := serveURL(t, h, nil, "a.ts", "b.ts")
+	ensure.DeepEqual(t, w.Body.String(), "let a = 1\n;\nlet a = 1")
+	ensure.DeepEqual(t, w.Header().Get("Content-Type"), "text/javascript; charset=utf-8")
+}
+
+func TestOutputExt(t *testing.T) {
+	h := &Handler{Compilers: map[string]Compiler{
+		".ts":   stripTypes{},
+		".scss": &CommandCompiler{Type: "text/css; charset=utf-8"},
+		".x":    &CommandCompiler{Type: "text/plain"},
+	}}
+	ensure.DeepEqual(t, h.outputExt("a.TS"), ".js")
+	ensure.DeepEqual(t, h.outputExt("a.scss"), ".css")
+	ensure.DeepEqual(t, h.outputExt("a.x"), ".x")
+	ensure.DeepEqual(t, h.outputExt("a.png"), ".png")
+}
+
+func TestCommandCompiler(t *testing.T) {
+	if _, err := exec.LookPath("tr"); err != nil {
+		t.Skip("tr not found")
+	}
+	c := &CommandCompiler{Command: []string{"tr", "a-z", "A-Z"}, Type: "text/css"}
+	v, err := c.Compile("a.css", []byte("a{}"))
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, string(v), "A{}")
+	ensure.DeepEqual(t, c.ContentType(), "text/css")
+}
+
+func TestCommandCompilerError(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not found")
+	}
+	h := &Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("x"), nil
+		}),
+		Compilers: map[string]Compiler{
+			".ts": &CommandCompiler{Command: []string{"sh", "-c", "echo bad input >&2; exit 1"}},
+		},
+	}
+	_, err := h.URL("a.ts")
+	ensure.Err(t, err, regexp.MustCompile(`^static: compile a.ts: exit status 1: bad input$`))
+
+	_, err = (&CommandCompiler{}).Compile("a.ts", nil)
+	ensure.Err(t, err, regexp.MustCompile("no command"))
+}
+
+func TestEsbuild(t *testing.T) {
+	c := Esbuild("ts", "--target=es2017")
+	ensure.DeepEqual(t, c.Command, []string{"esbuild", "--loader=ts", "--format=esm", "--target=es2017"})
+	ensure.DeepEqual(t, c.ContentType(), "text/javascript; charset=utf-8")
+}
+
+type scss struct{}
+
+func (scss) Compile(name string, in []byte) ([]byte, error) {
+	return bytes.ReplaceAll(in, []byte("$img"), []byte("url(a.png)")), nil
+}
+
+func (scss) ContentType() string {
+	return "text/css; charset=utf-8"
+}
+
+func TestSassStylesheet(t *testing.T) {
+	contents := map[string]string{
+		"css/app.scss": "a{background:$img}",
+		"css/a.png":    "foo",
+	}
+	h := &Handler{
+		Path:           "/",
+		RewriteCSSURLs: true,
+		Compilers:      map[string]Compiler{".scss": scss{}},
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte(contents[name]), nil
+		}),
+	}
+	w := serveURL(t, h, nil, "css/app.scss")
+	ensure.DeepEqual(t, w.Body.String(), "a{background:url(/W1siY3NzL2EucG5nIiwiYWNiZDE4ZGIiXV0.png)}")
+	ensure.DeepEqual(t, w.Header().Get("Content-Type"), "text/css; charset=utf-8")
+}
+
+func TestSass(t *testing.T) {
+	c := Sass("--indented")
+	ensure.DeepEqual(t, c.Command, []string{"sass", "--stdin", "--no-source-map", "--indented"})
+	ensure.DeepEqual(t, c.ContentType(), "text/css; charset=utf-8")
+}
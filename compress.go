@@ -0,0 +1,279 @@
+package static
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Encoder provides a content coding for responses, allowing compression such
+// as Brotli to be plugged in:
+//
+//	type brotliEncoder struct{}
+//
+//	func (brotliEncoder) Encoding() string { return "br" }
+//
+//	func (brotliEncoder) NewWriter(w io.Writer) io.WriteCloser {
+//		return brotli.NewWriterLevel(w, brotli.BestCompression)
+//	}
+type Encoder interface {
+	// Encoding returns the name of the coding, as used in Accept-Encoding.
+	Encoding() string
+
+	// NewWriter returns a writer which compresses to w, and is flushed by
+	// Close.
+	NewWriter(w io.Writer) io.WriteCloser
+}
+
+type gzipEncoder struct{}
+
+func (gzipEncoder) Encoding() string { return "gzip" }
+
+func (gzipEncoder) NewWriter(w io.Writer) io.WriteCloser {
+	zw, _ := gzip.NewWriterLevel(w, gzip.BestCompression)
+	return zw
+}
+
+// sidecars maps the content codings for which precompressed files are looked
+// up, in order of preference, to the extension of those files.
+var sidecars = []struct{ coding, ext string }{
+	{"br", ".br"},
+	{"zstd", ".zst"},
+	{"gzip", ".gz"},
+}
+
+// encoders returns the configured Encoders in order of preference.
+func (h *Handler) encoders() []Encoder {
+	if !h.Gzip {
+		return h.Encoders
+	}
+	return append(h.Encoders[:len(h.Encoders):len(h.Encoders)], gzipEncoder{})
+}
+
+// encoder returns the Encoder for the coding, or nil if there is none.
+func (h *Handler) encoder(coding string) Encoder {
+	for _, enc := range h.encoders() {
+		if enc.Encoding() == coding {
+			return enc
+		}
+	}
+	return nil
+}
+
+// codings returns the content codings which may be served in order of
+// preference. Those only available from precompressed files come after the
+// Encoders, except for gzip which is always last.
+func (h *Handler) codings() []string {
+	var codings []string
+	for _, enc := range h.Encoders {
+		codings = append(codings, enc.Encoding())
+	}
+	if h.Precompressed {
+		for _, sc := range sidecars {
+			if sc.coding != "gzip" && h.encoder(sc.coding) == nil {
+				codings = append(codings, sc.coding)
+			}
+		}
+	}
+	if h.Gzip || h.Precompressed {
+		codings = append(codings, "gzip")
+	}
+	return codings
+}
+
+func encodeBytes(enc Encoder, contents []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	ew := enc.NewWriter(&buf)
+	if _, err := ew.Write(contents); err != nil {
+		return nil, err
+	}
+	if err := ew.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encode returns the compressed forms of the named file for each coding,
+// using precompressed files from the Box where available and otherwise the
+// Encoders, unless its type is not compressible. Precompressed files are
+// ignored when the contents were transformed.
+func (h *Handler) encode(name string, contents []byte) (map[string][]byte, error) {
+	codings := h.codings()
+	if len(codings) == 0 {
+		return nil, nil
+	}
+	encoded := make(map[string][]byte, len(codings))
+	if h.Precompressed && !h.transformed(name) {
+		for _, sc := range sidecars {
+			if b, err := h.Box.Bytes(name + sc.ext); err == nil {
+				encoded[sc.coding] = b
+			}
+		}
+	}
+	if compressible(h.typeByExtension(path.Ext(name))) {
+		for _, enc := range h.encoders() {
+			if _, found := encoded[enc.Encoding()]; found {
+				continue
+			}
+			b, err := encodeBytes(enc, contents)
+			if err != nil {
+				return nil, err
+			}
+			encoded[enc.Encoding()] = b
+		}
+	}
+	if len(encoded) == 0 {
+		return nil, nil
+	}
+	return encoded, nil
+}
+
+// accepted returns the codings allowed by an Accept-Encoding header value, in
+// order of preference.
+func accepted(codings []string, header string) []string {
+	var allowed []string
+	for _, coding := range codings {
+		if acceptsEncoding(header, coding) {
+			allowed = append(allowed, coding)
+		}
+	}
+	return allowed
+}
+
+// acceptsEncoding reports if an Accept-Encoding header value allows the
+// coding, either by name or with a wildcard, and without a zero quality.
+func acceptsEncoding(header, coding string) bool {
+	accepted := false
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != coding && name != "*" {
+			continue
+		}
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if key == "q" {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if name == coding {
+			return q > 0
+		}
+		accepted = q > 0
+	}
+	return accepted
+}
+
+// compressible reports if compressing the content type is worthwhile. Images,
+// audio, video, fonts and archives are already compressed, and unknown types
+// are left alone so they can still be sniffed.
+func compressible(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	switch {
+	case mediaType == "":
+		return false
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "+xml"),
+		strings.HasSuffix(mediaType, "+json"):
+		return true
+	}
+	switch mediaType {
+	case "application/javascript", "application/json", "application/xml",
+		"application/wasm", "application/manifest+json", "font/ttf", "font/otf":
+		return true
+	}
+	return false
+}
+
+// setCoding sets the content coding of the response, along with its entity
+// tag.
+func setCoding(header http.Header, coding string) {
+	header.Set("Content-Encoding", coding)
+	if tag := header.Get("ETag"); tag != "" {
+		header.Set("ETag", codedETag(tag, coding))
+	}
+}
+
+// serveEncoded writes a compressed response using the first of the codings
+// possible, and reports if it did. A single file is served as compressed when
+// it was loaded, and combined files are compressed on first use and cached.
+// Responses including streamed files are compressed as they are written.
+func (h *Handler) serveEncoded(w http.ResponseWriter, files []file, contentType string, codings []string) bool {
+	if len(files) == 1 {
+		for _, coding := range codings {
+			if encoded, found := files[0].Encoded[coding]; found {
+				setCoding(w.Header(), coding)
+				h.writeHeaders(w, int64(len(encoded)), contentType)
+				w.Write(encoded)
+				return true
+			}
+		}
+	}
+	if !compressible(contentType) {
+		return false
+	}
+	for _, coding := range codings {
+		enc := h.encoder(coding)
+		if enc == nil {
+			continue
+		}
+		header := w.Header()
+		if !streamed(files) {
+			encoded, err := h.encodeBundle(files, enc)
+			if err == nil {
+				setCoding(header, coding)
+				h.writeHeaders(w, int64(len(encoded)), contentType)
+				w.Write(encoded)
+				return true
+			}
+			h.warn("static: compression failed", "name", files[0].Name, "coding", coding, "err", err)
+		}
+		setCoding(header, coding)
+		h.setCacheControl(header)
+		header.Set("Content-Type", contentType)
+		ew := enc.NewWriter(w)
+		if err := h.writeFiles(ew, files); err != nil {
+			h.warn("static: stream failed", "err", err)
+		}
+		ew.Close()
+		return true
+	}
+	return false
+}
+
+// encodeBundle returns the combined files compressed by the Encoder. They are
+// compressed once and kept in the file cache by the entity tag of the files,
+// counting toward CacheSize, so later requests need no compression.
+func (h *Handler) encodeBundle(files []file, enc Encoder) ([]byte, error) {
+	coding := enc.Encoding()
+	key := "\x00" + etag(files) + "\x00" + coding
+	if f, found := h.cache().get(key, h.CacheTTL); found {
+		return f.Encoded[coding], nil
+	}
+	v, err, _ := h.loads.Do(key, func() (interface{}, error) {
+		if f, found := h.cache().get(key, h.CacheTTL); found {
+			return f.Encoded[coding], nil
+		}
+		var buf bytes.Buffer
+		if err := h.writeFiles(&buf, files); err != nil {
+			return nil, err
+		}
+		encoded, err := encodeBytes(enc, buf.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		h.cache().add(file{Name: key, Encoded: map[string][]byte{coding: encoded}}, h.CacheSize, h.CacheTTL)
+		return encoded, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]byte), nil
+}
@@ -0,0 +1,349 @@
+package static
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/facebookgo/ensure"
+)
+
+func gunzip(t *testing.T, b []byte) string {
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	ensure.Nil(t, err)
+	contents, err := ioutil.ReadAll(zr)
+	ensure.Nil(t, err)
+	return string(contents)
+}
+
+func TestGzipStored(t *testing.T) {
+	var loads int
+	h := &Handler{
+		Path: "/",
+		Gzip: true,
+		Box: funcBox(func(name string) ([]byte, error) {
+			loads++
+			return []byte("body{color:red}"), nil
+		}),
+	}
+	v, err := h.URL("a.css")
+	ensure.Nil(t, err)
+
+	r := httptest.NewRequest("GET", v, nil)
+	r.Header.Set("Accept-Encoding", "gzip, deflate")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	ensure.DeepEqual(t, w.Header().Get("Content-Encoding"), "gzip")
+	ensure.DeepEqual(t, w.Header().Get("Vary"), "Accept-Encoding")
+	ensure.DeepEqual(t, gunzip(t, w.Body.Bytes()), "body{color:red}")
+	ensure.DeepEqual(t, loads, 1)
+	gzipTag := w.Header().Get("ETag")
+	ensure.StringContains(t, gzipTag, "-gzip")
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", v, nil))
+	ensure.DeepEqual(t, w.Header().Get("Content-Encoding"), "")
+	ensure.DeepEqual(t, w.Header().Get("Vary"), "Accept-Encoding")
+	ensure.DeepEqual(t, w.Body.String(), "body{color:red}")
+	ensure.DeepEqual(t, w.Header().Get("ETag"), strings.Replace(gzipTag, "-gzip", "", 1))
+
+	r = httptest.NewRequest("GET", v, nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	r.Header.Set("If-None-Match", gzipTag)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	ensure.DeepEqual(t, w.Code, 304)
+	ensure.DeepEqual(t, w.Header().Get("ETag"), gzipTag)
+
+	// the compressed tag does not match a response without compression
+	r = httptest.NewRequest("GET", v, nil)
+	r.Header.Set("If-None-Match", gzipTag)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	ensure.DeepEqual(t, w.Code, 200)
+}
+
+func TestGzipDisabled(t *testing.T) {
+	h := &Handler{
+		Path: "/",
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	}
+	v, err := h.URL("a.css")
+	ensure.Nil(t, err)
+	r := httptest.NewRequest("GET", v, nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	ensure.DeepEqual(t, w.Header().Get("Content-Encoding"), "")
+	ensure.DeepEqual(t, w.Body.String(), "foo")
+}
+
+func TestGzipCombined(t *testing.T) {
+	h := &Handler{
+		Path: "/",
+		Gzip: true,
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte(name), nil
+		}),
+	}
+	v, err := h.URL("a.js", "b.js")
+	ensure.Nil(t, err)
+	r := httptest.NewRequest("GET", v, nil)
+	r.Header.Set("Accept-Encoding", "br;q=1.0, gzip;q=0.8")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	ensure.DeepEqual(t, w.Header().Get("Content-Encoding"), "gzip")
+	ensure.DeepEqual(t, w.Header().Get("Content-Length"), strconv.Itoa(w.Body.Len()))
+	ensure.DeepEqual(t, gunzip(t, w.Body.Bytes()), "a.js\n;\nb.js")
+
+	// the compressed bundle is cached, and counts toward the cache size
+	files := []file{{Name: "a.js", Hash: h.hash([]byte("a.js"))}, {Name: "b.js", Hash: h.hash([]byte("b.js"))}}
+	cached, found := h.cache().get("\x00"+etag(files)+"\x00gzip", 0)
+	ensure.True(t, found)
+	ensure.DeepEqual(t, cached.Encoded["gzip"], w.Body.Bytes())
+	ensure.DeepEqual(t, h.Stats().Entries, 3)
+
+	body := w.Body.Bytes()
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	ensure.DeepEqual(t, w.Body.Bytes(), body)
+}
+
+func TestGzipCombinedStreamed(t *testing.T) {
+	h := &Handler{
+		Path:       "/",
+		Gzip:       true,
+		StreamSize: 4,
+		Box: FSBox(fstest.MapFS{
+			"a.js": {Data: []byte("0123456789")},
+			"b.js": {Data: []byte("b")},
+		}),
+	}
+	v, err := h.URL("a.js", "b.js")
+	ensure.Nil(t, err)
+	r := httptest.NewRequest("GET", v, nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	ensure.DeepEqual(t, w.Header().Get("Content-Encoding"), "gzip")
+	ensure.DeepEqual(t, w.Header().Get("Content-Length"), "")
+	ensure.DeepEqual(t, gunzip(t, w.Body.Bytes()), "0123456789\n;\nb")
+	ensure.DeepEqual(t, h.Stats().Entries, 2)
+}
+
+func TestGzipSkipsImages(t *testing.T) {
+	h := &Handler{
+		Path: "/",
+		Gzip: true,
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("png"), nil
+		}),
+	}
+	v, err := h.URL("a.png")
+	ensure.Nil(t, err)
+	cached, _ := h.cache().get("a.png", 0)
+	ensure.True(t, cached.Encoded == nil)
+	r := httptest.NewRequest("GET", v, nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	ensure.DeepEqual(t, w.Header().Get("Content-Encoding"), "")
+	ensure.DeepEqual(t, w.Body.String(), "png")
+}
+
+func TestAcceptsEncoding(t *testing.T) {
+	cases := map[string]bool{
+		"":                  false,
+		"gzip":              true,
+		"GZIP":              true,
+		"deflate, gzip":     true,
+		"gzip;q=0":          false,
+		"gzip; q=0.5":       true,
+		"*":                 true,
+		"*;q=0":             false,
+		"gzip;q=0, *":       false,
+		"*, gzip;q=0":       false,
+		"identity, deflate": false,
+	}
+	for header, expected := range cases {
+		ensure.DeepEqual(t, acceptsEncoding(header, "gzip"), expected, header)
+	}
+}
+
+func TestCompressible(t *testing.T) {
+	ensure.True(t, compressible("text/css; charset=utf-8"))
+	ensure.True(t, compressible("application/javascript"))
+	ensure.True(t, compressible("image/svg+xml"))
+	ensure.False(t, compressible("image/png"))
+	ensure.False(t, compressible("font/woff2"))
+	ensure.False(t, compressible(""))
+}
+
+// upperEncoder is a stand in for a real compressor, which upper cases.
+type upperEncoder struct{}
+
+func (upperEncoder) Encoding() string { return "upper" }
+
+func (upperEncoder) NewWriter(w io.Writer) io.WriteCloser {
+	return &upperWriter{w: w}
+}
+
+type upperWriter struct {
+	w io.Writer
+}
+
+func (u *upperWriter) Write(p []byte) (int, error) {
+	return u.w.Write(bytes.ToUpper(p))
+}
+
+func (u *upperWriter) Close() error { return nil }
+
+func TestEncoders(t *testing.T) {
+	h := &Handler{
+		Path:     "/",
+		Gzip:     true,
+		Encoders: []Encoder{upperEncoder{}},
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte(name), nil
+		}),
+	}
+	v, err := h.URL("a.css")
+	ensure.Nil(t, err)
+	cached, _ := h.cache().get("a.css", 0)
+	ensure.DeepEqual(t, string(cached.Encoded["upper"]), "A.CSS")
+	ensure.NotNil(t, cached.Encoded["gzip"])
+	ensure.DeepEqual(t, h.Stats().Bytes, cached.size())
+
+	r := httptest.NewRequest("GET", v, nil)
+	r.Header.Set("Accept-Encoding", "gzip, upper")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	ensure.DeepEqual(t, w.Header().Get("Content-Encoding"), "upper")
+	ensure.DeepEqual(t, w.Body.String(), "A.CSS")
+	ensure.StringContains(t, w.Header().Get("ETag"), "-upper")
+
+	r = httptest.NewRequest("GET", v, nil)
+	r.Header.Set("Accept-Encoding", "gzip, upper;q=0")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	ensure.DeepEqual(t, w.Header().Get("Content-Encoding"), "gzip")
+	ensure.StringContains(t, w.Header().Get("ETag"), "-gzip")
+
+	v, err = h.URL("a.css", "b.css")
+	ensure.Nil(t, err)
+	r = httptest.NewRequest("GET", v, nil)
+	r.Header.Set("Accept-Encoding", "upper")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	ensure.DeepEqual(t, w.Header().Get("Content-Encoding"), "upper")
+	ensure.DeepEqual(t, w.Body.String(), "A.CSS\nB.CSS")
+	ensure.DeepEqual(t, w.Header().Get("Content-Length"), "11")
+	files := []file{{Name: "a.css", Hash: h.hash([]byte("a.css"))}, {Name: "b.css", Hash: h.hash([]byte("b.css"))}}
+	cached, found := h.cache().get("\x00"+etag(files)+"\x00upper", 0)
+	ensure.True(t, found)
+	ensure.DeepEqual(t, string(cached.Encoded["upper"]), "A.CSS\nB.CSS")
+	tag := w.Header().Get("ETag")
+	ensure.StringContains(t, tag, "-upper")
+
+	r = httptest.NewRequest("GET", v, nil)
+	r.Header.Set("Accept-Encoding", "upper")
+	r.Header.Set("If-None-Match", tag)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	ensure.DeepEqual(t, w.Code, 304)
+}
+
+func TestPrecompressed(t *testing.T) {
+	h := &Handler{
+		Path:          "/",
+		Precompressed: true,
+		Box: FSBox(fstest.MapFS{
+			"app.js":    {Data: []byte("raw")},
+			"app.js.br": {Data: []byte("brotli")},
+			"app.js.gz": {Data: []byte("gzip")},
+			"other.css": {Data: []byte("other")},
+		}),
+	}
+	v, err := h.URL("app.js")
+	ensure.Nil(t, err)
+
+	serve := func(url, acceptEncoding string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", url, nil)
+		r.Header.Set("Accept-Encoding", acceptEncoding)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve(v, "gzip, br")
+	ensure.DeepEqual(t, w.Header().Get("Content-Encoding"), "br")
+	ensure.DeepEqual(t, w.Header().Get("Vary"), "Accept-Encoding")
+	ensure.DeepEqual(t, w.Body.String(), "brotli")
+
+	w = serve(v, "gzip")
+	ensure.DeepEqual(t, w.Header().Get("Content-Encoding"), "gzip")
+	ensure.DeepEqual(t, w.Body.String(), "gzip")
+
+	w = serve(v, "zstd")
+	ensure.DeepEqual(t, w.Header().Get("Content-Encoding"), "")
+	ensure.DeepEqual(t, w.Body.String(), "raw")
+
+	// without an Encoder there is nothing to compress other files with
+	v, err = h.URL("other.css")
+	ensure.Nil(t, err)
+	w = serve(v, "gzip, br")
+	ensure.DeepEqual(t, w.Header().Get("Content-Encoding"), "")
+	ensure.DeepEqual(t, w.Body.String(), "other")
+}
+
+func TestPrecompressedTransformed(t *testing.T) {
+	h := &Handler{
+		Path:          "/",
+		Gzip:          true,
+		Precompressed: true,
+		Box: FSBox(fstest.MapFS{
+			"app.js":    {Data: []byte("raw")},
+			"app.js.gz": {Data: []byte("gzip")},
+		}),
+	}
+	WithTransform(".js", func(name string, in []byte) ([]byte, error) {
+		return bytes.ToUpper(in), nil
+	})(h)
+	v, err := h.URL("app.js")
+	ensure.Nil(t, err)
+	r := httptest.NewRequest("GET", v, nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	ensure.DeepEqual(t, w.Header().Get("Content-Encoding"), "gzip")
+	ensure.DeepEqual(t, gunzip(t, w.Body.Bytes()), "RAW")
+}
+
+func TestPrecompressedWithGzip(t *testing.T) {
+	h := &Handler{
+		Path:          "/",
+		Gzip:          true,
+		Precompressed: true,
+		Box: FSBox(fstest.MapFS{
+			"app.js":    {Data: []byte("raw")},
+			"app.js.br": {Data: []byte("brotli")},
+		}),
+	}
+	v, err := h.URL("app.js")
+	ensure.Nil(t, err)
+	r := httptest.NewRequest("GET", v, nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	ensure.DeepEqual(t, w.Header().Get("Content-Encoding"), "gzip")
+	ensure.DeepEqual(t, gunzip(t, w.Body.Bytes()), "raw")
+	ensure.DeepEqual(t, h.codings(), []string{"br", "zstd", "gzip"})
+}
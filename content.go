@@ -0,0 +1,59 @@
+package static
+
+import (
+	"bytes"
+	"context"
+)
+
+// ContentResolver is a Resolver which also provides the contents of the files
+// it generates URLs for, such as to inline them into a page.
+type ContentResolver interface {
+	Resolver
+	ContentContext(ctx context.Context, names ...string) ([]byte, error)
+}
+
+var _ ContentResolver = (*Handler)(nil)
+
+// ContentContext returns the combined contents of the given names, as served
+// for their URL.
+func (h *Handler) ContentContext(ctx context.Context, names ...string) ([]byte, error) {
+	if len(names) == 0 {
+		return nil, errZeroNames
+	}
+	names, err := h.bundleNames(names)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]file, 0, len(names))
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		f, err := h.load(name)
+		if err != nil {
+			return nil, err
+		}
+		if f.Content == nil {
+			if f.Content, err = h.bytes(name); err != nil {
+				return nil, err
+			}
+			f.Streamed = 0
+		}
+		files = append(files, f)
+	}
+	var buf bytes.Buffer
+	if err := h.writeFiles(&buf, files); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Content returns the combined contents of the given names using the Resolver
+// in the context. It returns nil if the Resolver does not provide them.
+func Content(ctx context.Context, names ...string) ([]byte, error) {
+	r, ok := FromContext(ctx).(ContentResolver)
+	if !ok {
+		return nil, nil
+	}
+	return r.ContentContext(ctx, names...)
+}
@@ -0,0 +1,41 @@
+package static
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestContent(t *testing.T) {
+	contents := map[string]string{"a.css": "a{}", "b.css": "b{}"}
+	h := &Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte(contents[name]), nil
+		}),
+	}
+	v, err := Content(makeCtx(h), "a.css", "b.css", "a.css")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, string(v), "a{}\nb{}")
+}
+
+func TestContentErrors(t *testing.T) {
+	givenErr := errors.New("")
+	h := &Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			return nil, givenErr
+		}),
+	}
+	_, err := Content(makeCtx(h), "a.css")
+	ensure.DeepEqual(t, err, givenErr)
+	_, err = Content(makeCtx(h))
+	ensure.DeepEqual(t, err, errZeroNames)
+}
+
+func TestContentUnsupportedResolver(t *testing.T) {
+	v, err := Content(NewContext(context.Background(), urlResolver{}), "a.css")
+	ensure.Nil(t, err)
+	ensure.True(t, v == nil)
+}
@@ -0,0 +1,56 @@
+package static
+
+import (
+	"mime"
+	"strings"
+)
+
+// webTypes are the types of common web assets, which are used rather than
+// those from the system so responses do not vary between machines.
+var webTypes = map[string]string{
+	".css":         "text/css; charset=utf-8",
+	".html":        "text/html; charset=utf-8",
+	".js":          "text/javascript; charset=utf-8",
+	".mjs":         "text/javascript; charset=utf-8",
+	".json":        "application/json",
+	".map":         "application/json",
+	".webmanifest": "application/manifest+json",
+	".wasm":        "application/wasm",
+	".svg":         "image/svg+xml",
+	".png":         "image/png",
+	".jpg":         "image/jpeg",
+	".jpeg":        "image/jpeg",
+	".gif":         "image/gif",
+	".webp":        "image/webp",
+	".avif":        "image/avif",
+	".ico":         "image/x-icon",
+	".woff":        "font/woff",
+	".woff2":       "font/woff2",
+	".ttf":         "font/ttf",
+	".otf":         "font/otf",
+	".txt":         "text/plain; charset=utf-8",
+	".xml":         "text/xml; charset=utf-8",
+	".mp4":         "video/mp4",
+	".webm":        "video/webm",
+	".mp3":         "audio/mpeg",
+	".ogg":         "audio/ogg",
+}
+
+// typeByExtension returns the Content-Type for a file extension, or "" if it
+// is not known.
+func (h *Handler) typeByExtension(ext string) string {
+	if ext == "" {
+		return ""
+	}
+	ext = strings.ToLower(ext)
+	if t, found := h.ContentTypes[ext]; found {
+		return t
+	}
+	if c, found := h.Compilers[ext]; found {
+		return c.ContentType()
+	}
+	if t, found := webTypes[ext]; found {
+		return t
+	}
+	return mime.TypeByExtension(ext)
+}
@@ -0,0 +1,34 @@
+package static
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestTypeByExtension(t *testing.T) {
+	h := &Handler{ContentTypes: map[string]string{".glb": "model/gltf-binary"}}
+	ensure.DeepEqual(t, h.typeByExtension(".js"), "text/javascript; charset=utf-8")
+	ensure.DeepEqual(t, h.typeByExtension(".CSS"), "text/css; charset=utf-8")
+	ensure.DeepEqual(t, h.typeByExtension(".glb"), "model/gltf-binary")
+	ensure.DeepEqual(t, h.typeByExtension(""), "")
+	ensure.DeepEqual(t, h.typeByExtension(".unknown-ext"), "")
+}
+
+func TestContentTypeFromFirstFile(t *testing.T) {
+	h := &Handler{
+		Path:         "/",
+		ContentTypes: map[string]string{".tpl": "text/x-template"},
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte(name), nil
+		}),
+	}
+	v, err := h.URL("a.tpl", "b.js")
+	ensure.Nil(t, err)
+	cached, _ := h.cache().get("a.tpl", 0)
+	ensure.DeepEqual(t, cached.ContentType, "text/x-template")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", v, nil))
+	ensure.DeepEqual(t, w.Header().Get("Content-Type"), "text/x-template")
+}
@@ -0,0 +1,256 @@
+package static
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	cssURL     = regexp.MustCompile(`url\(\s*(['"]?)([^'")]+)(['"]?)\s*\)`)
+	cssImport  = regexp.MustCompile(`@import\s+(?:url\(\s*)?['"]?([^'")\s;]+)['"]?\s*\)?\s*([^;]*);`)
+	cssCharset = regexp.MustCompile(`^@charset\s+"[^"]*"\s*;`)
+)
+
+// ErrCSSImportCycle is returned when stylesheets being flattened import each
+// other.
+type ErrCSSImportCycle struct {
+	Names []string // The chain of imports, ending with the repeated name.
+}
+
+func (e *ErrCSSImportCycle) Error() string {
+	return fmt.Sprintf("static: css import cycle: %s", strings.Join(e.Names, " -> "))
+}
+
+// transformCSS applies the configured stylesheet rewrites. The stack holds the
+// stylesheets importing this one, and the files referenced are added to deps.
+func (h *Handler) transformCSS(name string, contents []byte, stack []string, deps *[]string) ([]byte, error) {
+	if h.RewriteCSSURLs {
+		contents = h.rewriteCSSURLs(name, contents, deps)
+	}
+	if h.FlattenCSSImports {
+		return h.flattenCSSImports(name, contents, append(stack, name), deps)
+	}
+	return contents, nil
+}
+
+// flattenCSSImports replaces relative @import rules with the transformed
+// contents of the imported stylesheets. Imports which cannot be flattened,
+// such as those of other sites or of missing files, are left as @import rules
+// and moved to the top, after any @charset, as browsers ignore an @import
+// following other rules.
+func (h *Handler) flattenCSSImports(name string, contents []byte, stack []string, deps *[]string) ([]byte, error) {
+	charset := cssCharset.Find(contents)
+	top := name
+	if len(stack) > 0 {
+		top = stack[0]
+	}
+	imports, body, err := h.flattenImports(top, name, contents[len(charset):], stack, deps)
+	if err != nil {
+		return nil, err
+	}
+	out := append([]byte{}, charset...)
+	for _, rule := range imports {
+		out = append(out, rule...)
+	}
+	return append(out, body...), nil
+}
+
+// flattenImports returns the @import rules of the stylesheet which are kept,
+// with relative references rebased onto the top stylesheet, along with the
+// rest of the stylesheet where the other rules are replaced by the imported
+// contents.
+func (h *Handler) flattenImports(top, name string, contents []byte, stack []string, deps *[]string) ([][]byte, []byte, error) {
+	var imports [][]byte
+	var err error
+	keep := func(match []byte, m [][]byte) {
+		if ref := string(m[1]); relativeURL(ref) && path.Dir(name) != path.Dir(top) {
+			rebased := rebaseURL(path.Dir(top), path.Join(path.Dir(name), ref))
+			match = bytes.Replace(match, m[1], []byte(rebased), 1)
+		}
+		imports = append(imports, match)
+	}
+	body := cssImport.ReplaceAllFunc(contents, func(match []byte) []byte {
+		if err != nil {
+			return nil
+		}
+		m := cssImport.FindSubmatch(match)
+		ref := string(m[1])
+		if !relativeURL(ref) {
+			keep(match, m)
+			return nil
+		}
+		target := path.Join(path.Dir(name), ref)
+		for _, s := range stack {
+			if s == target {
+				err = &ErrCSSImportCycle{Names: append(append([]string{}, stack...), target)}
+				return nil
+			}
+		}
+		*deps = append(*deps, target)
+		imported, rerr := h.boxBytes(target)
+		if rerr != nil {
+			h.warn("static: css import failed", "name", name, "import", ref, "err", rerr)
+			keep(match, m)
+			return nil
+		}
+		if h.RewriteCSSURLs {
+			imported = h.rewriteCSSURLs(target, imported, deps)
+		}
+		imported = imported[len(cssCharset.Find(imported)):]
+		nested, inner, ierr := h.flattenImports(top, target, imported, append(stack, target), deps)
+		if ierr != nil {
+			err = ierr
+			return nil
+		}
+		conditions := strings.TrimSpace(string(m[2]))
+		for _, rule := range nested {
+			imports = append(imports, withConditions(rule, conditions))
+		}
+		return wrapImport(conditions, inner)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return imports, body, nil
+}
+
+// withConditions adds the conditions of the importing rule to a kept @import
+// rule from the imported stylesheet, unless it has conditions of its own.
+func withConditions(rule []byte, conditions string) []byte {
+	m := cssImport.FindSubmatch(rule)
+	if conditions == "" || strings.TrimSpace(string(m[2])) != "" {
+		return rule
+	}
+	return append(bytes.TrimSuffix(rule, []byte(";")), []byte(" "+conditions+";")...)
+}
+
+// rebaseURL returns the reference to the named file from the directory dir.
+func rebaseURL(dir, name string) string {
+	rel, err := filepath.Rel(filepath.FromSlash(dir), filepath.FromSlash(name))
+	if err != nil {
+		return name
+	}
+	return filepath.ToSlash(rel)
+}
+
+// cssDeclaration matches a supports() condition which is a bare declaration,
+// such as "display: grid", rather than a full condition.
+var cssDeclaration = regexp.MustCompile(`^[\w-]+\s*:`)
+
+// wrapImport wraps the imported contents in the blocks equivalent to the
+// layer(), supports() and media query conditions of the @import rule.
+func wrapImport(conditions string, contents []byte) []byte {
+	out := string(contents)
+	conditions = strings.TrimSpace(conditions)
+	var layer *string
+	if strings.HasPrefix(conditions, "layer(") {
+		if i := strings.IndexByte(conditions, ')'); i >= 0 {
+			name := strings.TrimSpace(conditions[len("layer("):i])
+			layer = &name
+			conditions = conditions[i+1:]
+		}
+	} else if conditions == "layer" || strings.HasPrefix(conditions, "layer ") {
+		layer = new(string)
+		conditions = conditions[len("layer"):]
+	}
+	conditions = strings.TrimSpace(conditions)
+	var supports string
+	if strings.HasPrefix(conditions, "supports(") {
+		depth := 0
+		for i, c := range conditions {
+			if c == '(' {
+				depth++
+			} else if c == ')' {
+				if depth--; depth == 0 {
+					supports = strings.TrimSpace(conditions[len("supports("):i])
+					conditions = conditions[i+1:]
+					break
+				}
+			}
+		}
+	}
+	if layer != nil {
+		if *layer == "" {
+			out = "@layer{" + out + "}"
+		} else {
+			out = "@layer " + *layer + "{" + out + "}"
+		}
+	}
+	if supports != "" {
+		if cssDeclaration.MatchString(supports) {
+			supports = "(" + supports + ")"
+		}
+		out = "@supports " + supports + "{" + out + "}"
+	}
+	if media := strings.TrimSpace(conditions); media != "" {
+		out = "@media " + media + "{" + out + "}"
+	}
+	return []byte(out)
+}
+
+// rewriteCSSURLs replaces the relative url() references in the stylesheet
+// with hashed URLs or data URIs. References which cannot be loaded are left as
+// is, so one missing file does not break the whole stylesheet. The referenced
+// names are added to deps, as the stylesheet changes along with them.
+func (h *Handler) rewriteCSSURLs(name string, contents []byte, deps *[]string) []byte {
+	return cssURL.ReplaceAllFunc(contents, func(match []byte) []byte {
+		m := cssURL.FindSubmatch(match)
+		ref := string(m[2])
+		if !relativeURL(ref) {
+			return match
+		}
+		ref, fragment := splitFragment(ref)
+		target := path.Join(path.Dir(name), ref)
+		if path.Ext(target) == ".css" || validName(target) != nil || strings.HasPrefix(target, "../") {
+			return match
+		}
+		*deps = append(*deps, target)
+		url, err := h.cssURL(target)
+		if err != nil {
+			h.warn("static: css url failed", "name", name, "url", ref, "err", err)
+			return match
+		}
+		return []byte("url(" + string(m[1]) + url + fragment + string(m[3]) + ")")
+	})
+}
+
+// cssURL returns the data URI for the file if it is small enough, or its
+// hashed URL.
+func (h *Handler) cssURL(name string) (string, error) {
+	ctx := context.Background()
+	if h.CSSInlineSize > 0 {
+		uri, err := h.DataURIContext(ctx, name, h.CSSInlineSize)
+		if err != nil || uri != "" {
+			return uri, err
+		}
+	}
+	return h.URLContext(ctx, name)
+}
+
+// relativeURL reports if the url() reference is relative to the stylesheet.
+func relativeURL(ref string) bool {
+	ref = strings.TrimSpace(ref)
+	return ref != "" &&
+		!strings.HasPrefix(ref, "/") &&
+		!strings.HasPrefix(ref, "#") &&
+		!strings.HasPrefix(ref, "data:") &&
+		!strings.Contains(ref, "://")
+}
+
+// splitFragment removes the query and fragment from ref, returning the
+// fragment, such as "#iefix", to be kept on the rewritten URL.
+func splitFragment(ref string) (string, string) {
+	var fragment string
+	if i := strings.IndexByte(ref, '#'); i >= 0 {
+		ref, fragment = ref[:i], ref[i:]
+	}
+	if i := strings.IndexByte(ref, '?'); i >= 0 {
+		ref = ref[:i]
+	}
+	return ref, fragment
+}
@@ -0,0 +1,199 @@
+package static
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestRewriteCSSURLs(t *testing.T) {
+	contents := map[string]string{
+		"css/app.css": `a{background:url(../img/a.png)}` +
+			`b{background:url( "b.png?x=1#y" )}` +
+			`c{background:url(/abs.png)}` +
+			`d{background:url(data:image/png;base64,AA==)}` +
+			`e{background:url(https://example.com/e.png)}` +
+			`f{background:url('missing.png')}`,
+		"img/a.png":   "foo",
+		"css/b.png":   "foo",
+		"css/big.png": "foobar",
+	}
+	h := &Handler{
+		Path:           "/static/",
+		RewriteCSSURLs: true,
+		Box: funcBox(func(name string) ([]byte, error) {
+			if c, found := contents[name]; found {
+				return []byte(c), nil
+			}
+			return nil, fs.ErrNotExist
+		}),
+	}
+	v, err := h.ContentContext(makeCtx(h), "css/app.css")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, string(v),
+		`a{background:url(/static/W1siaW1nL2EucG5nIiwiYWNiZDE4ZGIiXV0.png)}`+
+			`b{background:url("/static/W1siY3NzL2IucG5nIiwiYWNiZDE4ZGIiXV0.png#y")}`+
+			`c{background:url(/abs.png)}`+
+			`d{background:url(data:image/png;base64,AA==)}`+
+			`e{background:url(https://example.com/e.png)}`+
+			`f{background:url('missing.png')}`)
+}
+
+func TestRewriteCSSURLsDependencyChanged(t *testing.T) {
+	box := fstest.MapFS{
+		"app.css": {Data: []byte(`a{background:url(a.png)}`), ModTime: time.Unix(1, 0)},
+		"a.png":   {Data: []byte("foo"), ModTime: time.Unix(1, 0)},
+	}
+	h := &Handler{
+		Path:           "/",
+		RewriteCSSURLs: true,
+		CheckModTime:   true,
+		Box:            FSBox(box),
+	}
+	v1, err := h.ContentContext(makeCtx(h), "app.css")
+	ensure.Nil(t, err)
+	box["a.png"] = &fstest.MapFile{Data: []byte("bar"), ModTime: time.Unix(2, 0)}
+	v2, err := h.ContentContext(makeCtx(h), "app.css")
+	ensure.Nil(t, err)
+	ensure.NotDeepEqual(t, string(v2), string(v1))
+	u, err := h.URL("a.png")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, string(v2), "a{background:url("+u+")}")
+}
+
+func TestRewriteCSSURLsInline(t *testing.T) {
+	contents := map[string]string{
+		"app.css": `a{background:url(a.png)}b{background:url(big.png)}`,
+		"a.png":   "foo",
+		"big.png": "foobar",
+	}
+	h := &Handler{
+		Path:           "/",
+		RewriteCSSURLs: true,
+		CSSInlineSize:  3,
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte(contents[name]), nil
+		}),
+	}
+	v, err := h.ContentContext(makeCtx(h), "app.css")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, string(v),
+		`a{background:url(data:image/png;base64,Zm9v)}`+
+			`b{background:url(/W1siYmlnLnBuZyIsIjM4NThmNjIyIl1d.png)}`)
+}
+
+func TestRewriteCSSURLsDisabled(t *testing.T) {
+	h := &Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			if name != "app.css" {
+				return nil, errors.New("not reached")
+			}
+			return []byte(`a{background:url(a.png)}`), nil
+		}),
+	}
+	v, err := h.ContentContext(makeCtx(h), "app.css")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, string(v), `a{background:url(a.png)}`)
+}
+
+func TestFlattenCSSImports(t *testing.T) {
+	contents := map[string]string{
+		"app.css":        `@charset "utf-8";@import "base/reset.css";@import url('print.css') print;@import "https://example.com/x.css";a{}`,
+		"base/reset.css": `@charset "utf-8";@import "../fonts.css";@import "missing.css";b{background:url(b.png)}`,
+		"fonts.css":      `c{}`,
+		"print.css":      `d{}`,
+		"base/b.png":     `foo`,
+	}
+	h := &Handler{
+		Path:              "/",
+		RewriteCSSURLs:    true,
+		FlattenCSSImports: true,
+		Box: funcBox(func(name string) ([]byte, error) {
+			if c, found := contents[name]; found {
+				return []byte(c), nil
+			}
+			return nil, fs.ErrNotExist
+		}),
+	}
+	v, err := h.ContentContext(makeCtx(h), "app.css")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, string(v),
+		`@charset "utf-8";`+
+			`@import "base/missing.css";`+
+			`@import "https://example.com/x.css";`+
+			`c{}b{background:url(/W1siYmFzZS9iLnBuZyIsImFjYmQxOGRiIl1d.png)}`+
+			`@media print{d{}}`+
+			`a{}`)
+}
+
+func TestFlattenCSSImportsCycle(t *testing.T) {
+	contents := map[string]string{
+		"a.css": `@import "b.css";`,
+		"b.css": `@import "a.css";`,
+	}
+	h := &Handler{
+		FlattenCSSImports: true,
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte(contents[name]), nil
+		}),
+	}
+	_, err := h.URL("a.css")
+	ensure.DeepEqual(t, err, &ErrCSSImportCycle{Names: []string{"a.css", "b.css", "a.css"}})
+	ensure.DeepEqual(t, err.Error(), "static: css import cycle: a.css -> b.css -> a.css")
+}
+
+func TestFlattenCSSImportsMissing(t *testing.T) {
+	h := &Handler{
+		FlattenCSSImports: true,
+		Box: funcBox(func(name string) ([]byte, error) {
+			switch name {
+			case "a.css":
+				return []byte(`@import "c.css";@import "b.css";a{}`), nil
+			case "c.css":
+				return []byte(`@import "d.css" print;c{}`), nil
+			}
+			return nil, fs.ErrNotExist
+		}),
+	}
+	v, err := h.ContentContext(makeCtx(h), "a.css")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, string(v), `@import "d.css" print;@import "b.css";c{}a{}`)
+
+	// the stylesheet depends on the import, so it is reloaded once it exists
+	var deps []string
+	_, err = h.flattenCSSImports("a.css", []byte(`@import "b.css";`), nil, &deps)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, deps, []string{"b.css"})
+}
+
+func TestFlattenCSSImportsConditions(t *testing.T) {
+	h := &Handler{
+		FlattenCSSImports: true,
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("a{}"), nil
+		}),
+	}
+	cases := []struct{ conditions, flat string }{
+		{`layer(base)`, `@layer base{a{}}`},
+		{`layer`, `@layer{a{}}`},
+		{`layer(base) screen`, `@media screen{@layer base{a{}}}`},
+		{`supports(display: grid) screen`, `@media screen{@supports (display: grid){a{}}}`},
+		{`layer(x) supports((a: b) and (c: d))`, `@supports (a: b) and (c: d){@layer x{a{}}}`},
+		{`screen and (min-width: 1px)`, `@media screen and (min-width: 1px){a{}}`},
+	}
+	for _, c := range cases {
+		v, err := h.flattenCSSImports("app.css", []byte(`@import "b.css" `+c.conditions+`;`), nil, new([]string))
+		ensure.Nil(t, err)
+		ensure.DeepEqual(t, string(v), c.flat, c.conditions)
+	}
+}
+
+func TestWithConditions(t *testing.T) {
+	ensure.DeepEqual(t, string(withConditions([]byte(`@import "a.css";`), "print")), `@import "a.css" print;`)
+	ensure.DeepEqual(t, string(withConditions([]byte(`@import "a.css" screen;`), "print")), `@import "a.css" screen;`)
+	ensure.DeepEqual(t, string(withConditions([]byte(`@import "a.css";`), "")), `@import "a.css";`)
+}
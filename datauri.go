@@ -0,0 +1,46 @@
+package static
+
+import (
+	"context"
+	"encoding/base64"
+	"path"
+	"strings"
+)
+
+// DataURIResolver is a Resolver which can also embed small files as data URIs,
+// saving a request for each.
+type DataURIResolver interface {
+	Resolver
+	DataURIContext(ctx context.Context, name string, maxSize int) (string, error)
+}
+
+var _ DataURIResolver = (*Handler)(nil)
+
+// DataURIContext returns the contents of the file as a base64 data URI, or an
+// empty string if it is larger than maxSize bytes.
+func (h *Handler) DataURIContext(ctx context.Context, name string, maxSize int) (string, error) {
+	contents, err := h.ContentContext(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	if len(contents) > maxSize {
+		return "", nil
+	}
+	contentType := strings.Replace(h.typeByExtension(path.Ext(name)), " ", "", -1)
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(contents), nil
+}
+
+// DataURI returns the file as a data URI using the Resolver in the context, if
+// it is no larger than maxSize bytes. It returns an empty string if the file is
+// larger or the Resolver does not provide them, in which case URL should be
+// used instead.
+func DataURI(ctx context.Context, name string, maxSize int) (string, error) {
+	r, ok := FromContext(ctx).(DataURIResolver)
+	if !ok {
+		return "", nil
+	}
+	return r.DataURIContext(ctx, name, maxSize)
+}
@@ -0,0 +1,45 @@
+package static
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestDataURI(t *testing.T) {
+	h := &Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	}
+	v, err := DataURI(makeCtx(h), "a.png", 3)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, "data:image/png;base64,Zm9v")
+
+	v, err = DataURI(makeCtx(h), "a.css", 3)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, "data:text/css;charset=utf-8;base64,Zm9v")
+
+	v, err = DataURI(makeCtx(h), "a.png", 2)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, "")
+}
+
+func TestDataURIUnknownType(t *testing.T) {
+	h := &Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	}
+	v, err := DataURI(makeCtx(h), "a", 3)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, "data:application/octet-stream;base64,Zm9v")
+}
+
+func TestDataURIUnsupportedResolver(t *testing.T) {
+	v, err := DataURI(NewContext(context.Background(), urlResolver{}), "a.png", 3)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, "")
+}
@@ -0,0 +1,63 @@
+package static
+
+import (
+	"context"
+	"fmt"
+)
+
+// devRawPath is the RawPath used in Dev mode if none is set.
+const devRawPath = "dev/"
+
+// DevResolver is a Resolver which reports if it is in development mode, where
+// components render files separately to ease debugging.
+type DevResolver interface {
+	Resolver
+	DevMode() bool
+}
+
+var _ DevResolver = (*Handler)(nil)
+
+// DevMode reports if Dev is set.
+func (h *Handler) DevMode() bool {
+	return h.Dev
+}
+
+// DevMode reports if the Resolver in the context is in development mode.
+func DevMode(ctx context.Context) bool {
+	r, ok := FromContext(ctx).(DevResolver)
+	return ok && r.DevMode()
+}
+
+// rawPath returns the RawPath, defaulting to devRawPath in Dev mode.
+func (h *Handler) rawPath() string {
+	if h.RawPath == "" && h.Dev {
+		return devRawPath
+	}
+	return h.RawPath
+}
+
+// loadDev reads the file without caching it, including the name in errors.
+func (h *Handler) loadDev(name string) (file, error) {
+	f, err := h.read(name)
+	if err != nil {
+		return file{}, fmt.Errorf("static: %s: %w", name, err)
+	}
+	return f, nil
+}
+
+// devURL returns the raw URL for a single file, or the combined URL for many.
+// The files are loaded so missing ones fail when generating the URL.
+func (h *Handler) devURL(names []string) (string, error) {
+	files := make([]file, 0, len(names))
+	for _, name := range names {
+		f, err := h.load(name)
+		if err != nil {
+			return "", err
+		}
+		files = append(files, f)
+	}
+	if len(files) == 1 {
+		return h.RawURL(files[0].Name)
+	}
+	return h.makeURL(files)
+}
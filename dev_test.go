@@ -0,0 +1,74 @@
+package static
+
+import (
+	"errors"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestDevURL(t *testing.T) {
+	h := &Handler{
+		Path: "/static/",
+		Dev:  true,
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	}
+	v, err := h.URL("a.js")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, "/static/dev/a.js")
+
+	h.RawPath = "raw/"
+	v, err = h.URL("a.js")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, "/static/raw/a.js")
+}
+
+func TestDevServesCurrent(t *testing.T) {
+	contents := "foo"
+	h := &Handler{
+		Path: "/static/",
+		Dev:  true,
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte(contents), nil
+		}),
+	}
+	single, err := h.URL("a.js")
+	ensure.Nil(t, err)
+	combined, err := h.URL("a.js", "b.js")
+	ensure.Nil(t, err)
+	contents = "bar"
+
+	for _, u := range []string{single, combined} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", u, nil))
+		ensure.DeepEqual(t, w.Code, 200)
+		ensure.DeepEqual(t, w.Header().Get("Cache-Control"), "no-cache")
+		ensure.StringContains(t, w.Body.String(), "bar")
+	}
+	ensure.DeepEqual(t, h.cache().len(), 0)
+}
+
+func TestDevErrorName(t *testing.T) {
+	givenErr := errors.New("boom")
+	h := &Handler{
+		Dev: true,
+		Box: funcBox(func(name string) ([]byte, error) {
+			return nil, givenErr
+		}),
+	}
+	_, err := h.URL("a.js")
+	ensure.Err(t, err, regexp.MustCompile(`^static: a.js: boom$`))
+	ensure.True(t, errors.Is(err, givenErr))
+}
+
+func TestDevMode(t *testing.T) {
+	ensure.True(t, DevMode(makeCtx(&Handler{Dev: true})))
+	ensure.False(t, DevMode(makeCtx(&Handler{})))
+	ensure.False(t, DevMode(NewContext(context.Background(), urlResolver{})))
+}
@@ -0,0 +1,69 @@
+package static
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// diskPath returns the path in CacheDir for the combined files. It is derived
+// from the names and hashes, so a stale file is never served for a URL.
+func (h *Handler) diskPath(files []file) (string, error) {
+	key, err := encode(files)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(h.CacheDir, hex.EncodeToString(sum[:])), nil
+}
+
+// serveDisk serves the combined files from CacheDir, and reports if it did.
+func (h *Handler) serveDisk(w http.ResponseWriter, files []file, contentType string) bool {
+	p, err := h.diskPath(files)
+	if err != nil {
+		return false
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	h.writeHeaders(w, fi.Size(), contentType)
+	io.Copy(w, f)
+	return true
+}
+
+// writeDisk writes the combined contents of the resolved files to CacheDir.
+// The file is renamed into place so readers never see partial contents.
+func (h *Handler) writeDisk(files []file) error {
+	p, err := h.diskPath(files)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(p); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(h.CacheDir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(h.CacheDir, ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := h.writeFiles(tmp, files); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
@@ -0,0 +1,65 @@
+package static
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestDiskCache(t *testing.T) {
+	dir := t.TempDir()
+	h := &Handler{
+		Path:     "/",
+		CacheDir: dir,
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte(name), nil
+		}),
+	}
+	v, err := h.URL("a.css", "b.css")
+	ensure.Nil(t, err)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", v, nil))
+	ensure.DeepEqual(t, w.Body.String(), "a.css\nb.css")
+	entries, err := os.ReadDir(dir)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, len(entries), 1)
+
+	// a new Handler with a failing Box serves from disk
+	h2 := &Handler{
+		Path:     "/",
+		CacheDir: dir,
+		Box: funcBox(func(name string) ([]byte, error) {
+			return nil, errors.New("not reached")
+		}),
+	}
+	w = httptest.NewRecorder()
+	h2.ServeHTTP(w, httptest.NewRequest("GET", v, nil))
+	ensure.DeepEqual(t, w.Code, http.StatusOK)
+	ensure.DeepEqual(t, w.Body.String(), "a.css\nb.css")
+	ensure.DeepEqual(t, w.Header().Get("Content-Length"), "11")
+	ensure.DeepEqual(t, w.Header().Get("Content-Type"), "text/css; charset=utf-8")
+}
+
+func TestDiskCacheWriteError(t *testing.T) {
+	dir := t.TempDir()
+	blocker := filepath.Join(dir, "file")
+	ensure.Nil(t, os.WriteFile(blocker, nil, 0644))
+	h := &Handler{
+		Path:     "/",
+		CacheDir: filepath.Join(blocker, "cache"),
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	}
+	v, err := h.URL("a.css")
+	ensure.Nil(t, err)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", v, nil))
+	ensure.DeepEqual(t, w.Code, http.StatusOK)
+	ensure.DeepEqual(t, w.Body.String(), "foo")
+}
@@ -0,0 +1,40 @@
+package static
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// etag returns a strong entity tag for the combined files. As URLs include
+// the hashes of their files, it only changes along with the contents.
+func etag(files []file) string {
+	hs := sha256.New()
+	for _, f := range files {
+		hs.Write([]byte(f.Name + "\x00" + f.Hash + "\x00"))
+	}
+	return `"` + hex.EncodeToString(hs.Sum(nil))[:32] + `"`
+}
+
+// suffixETag derives the entity tag of another representation of the files.
+func suffixETag(tag, suffix string) string {
+	return strings.TrimSuffix(tag, `"`) + "-" + suffix + `"`
+}
+
+// codedETag returns the entity tag of the contents in a content coding, which
+// must differ from that of the identity encoding as the bytes differ.
+func codedETag(tag, coding string) string {
+	return suffixETag(tag, coding)
+}
+
+// etagMatch reports if an If-None-Match header value matches the tag, using
+// the weak comparison required for it.
+func etagMatch(header, tag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package static
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestETag(t *testing.T) {
+	var loads int
+	h := &Handler{
+		Path: "/",
+		Box: funcBox(func(name string) ([]byte, error) {
+			loads++
+			return []byte("foo"), nil
+		}),
+	}
+	v, err := h.URL("a.css")
+	ensure.Nil(t, err)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", v, nil))
+	ensure.DeepEqual(t, w.Code, http.StatusOK)
+	tag := w.Header().Get("ETag")
+	ensure.DeepEqual(t, len(tag), 34)
+
+	r := httptest.NewRequest("GET", v, nil)
+	r.Header.Set("If-None-Match", `"other", W/`+tag)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	ensure.DeepEqual(t, w.Code, http.StatusNotModified)
+	ensure.DeepEqual(t, w.Header().Get("ETag"), tag)
+	ensure.DeepEqual(t, w.Body.Len(), 0)
+	ensure.DeepEqual(t, loads, 1)
+
+	r = httptest.NewRequest("GET", v, nil)
+	r.Header.Set("If-None-Match", `"other"`)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	ensure.DeepEqual(t, w.Code, http.StatusOK)
+}
+
+func TestETagDiffers(t *testing.T) {
+	a := etag([]file{{Name: "a", Hash: "1"}})
+	ensure.NotDeepEqual(t, a, etag([]file{{Name: "a", Hash: "2"}}))
+	ensure.NotDeepEqual(t, a, etag([]file{{Name: "a", Hash: "1"}, {Name: "b", Hash: "1"}}))
+}
+
+func TestETagMatch(t *testing.T) {
+	ensure.True(t, etagMatch(`"a"`, `"a"`))
+	ensure.True(t, etagMatch(`*`, `"a"`))
+	ensure.True(t, etagMatch(`"b" , W/"a"`, `"a"`))
+	ensure.False(t, etagMatch(``, `"a"`))
+	ensure.False(t, etagMatch(`"b"`, `"a"`))
+}
+
+func TestCodedETag(t *testing.T) {
+	ensure.DeepEqual(t, codedETag(`"abc"`, "br"), `"abc-br"`)
+}
@@ -0,0 +1,46 @@
+package static
+
+import (
+	"net/http"
+)
+
+// Fallback is what a Handler does for URLs whose hashes are stale.
+type Fallback int
+
+const (
+	// FallbackNone fails with 404 Not Found, or 410 Gone with GoneStale.
+	FallbackNone Fallback = iota
+
+	// FallbackServe serves the current files without caching.
+	FallbackServe
+
+	// FallbackRedirect redirects to the current URL for the files.
+	FallbackRedirect
+)
+
+// current returns the resolved current versions of the files.
+func (h *Handler) current(files []file) ([]file, error) {
+	current := make([]file, len(files))
+	for i, f := range files {
+		loaded, err := h.load(f.Name)
+		if err != nil {
+			return nil, err
+		}
+		current[i] = file{Name: f.Name, Hash: loaded.Hash}
+	}
+	if err := h.resolve(current); err != nil {
+		return nil, err
+	}
+	return current, nil
+}
+
+func (h *Handler) redirectCurrent(w http.ResponseWriter, r *http.Request, files []file) {
+	u, err := h.makeURL(files)
+	if err != nil {
+		h.writeFailure(w, r, http.StatusNotFound)
+		return
+	}
+	w.Header().Del("ETag")
+	disableCaching(w)
+	http.Redirect(w, r, u, http.StatusFound)
+}
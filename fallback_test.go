@@ -0,0 +1,81 @@
+package static
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+)
+
+func staleHandler(fallback Fallback) (*Handler, *string) {
+	contents := "old"
+	return &Handler{
+		Path:          "/",
+		StaleFallback: fallback,
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte(contents), nil
+		}),
+	}, &contents
+}
+
+func TestFallbackNone(t *testing.T) {
+	h, contents := staleHandler(FallbackNone)
+	v, err := h.URL("a.css")
+	ensure.Nil(t, err)
+	*contents = "new"
+	h.ClearCache()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", v, nil))
+	ensure.DeepEqual(t, w.Code, http.StatusNotFound)
+}
+
+func TestFallbackServe(t *testing.T) {
+	h, contents := staleHandler(FallbackServe)
+	v, err := h.URL("a.css", "b.css")
+	ensure.Nil(t, err)
+	*contents = "new"
+	h.ClearCache()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", v, nil))
+	ensure.DeepEqual(t, w.Code, http.StatusOK)
+	ensure.DeepEqual(t, w.Body.String(), "new\nnew")
+	ensure.DeepEqual(t, w.Header().Get("Cache-Control"), "no-cache")
+
+	current, err := h.URL("a.css", "b.css")
+	ensure.Nil(t, err)
+	w2 := httptest.NewRecorder()
+	h.ServeHTTP(w2, httptest.NewRequest("GET", current, nil))
+	ensure.DeepEqual(t, w.Header().Get("ETag"), w2.Header().Get("ETag"))
+}
+
+func TestFallbackRedirect(t *testing.T) {
+	h, contents := staleHandler(FallbackRedirect)
+	v, err := h.URL("a.css")
+	ensure.Nil(t, err)
+	*contents = "new"
+	h.ClearCache()
+	current, err := h.URL("a.css")
+	ensure.Nil(t, err)
+	ensure.NotDeepEqual(t, v, current)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", v, nil))
+	ensure.DeepEqual(t, w.Code, http.StatusFound)
+	ensure.DeepEqual(t, w.Header().Get("Location"), current)
+	ensure.DeepEqual(t, w.Header().Get("Cache-Control"), "no-cache")
+}
+
+func TestFallbackUnknownHash(t *testing.T) {
+	h := &Handler{
+		Path:          "/",
+		StaleFallback: FallbackServe,
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/W1siZm9vIiwiYmFyIl1d", nil))
+	ensure.DeepEqual(t, w.Code, http.StatusOK)
+	ensure.DeepEqual(t, w.Body.String(), "foo")
+}
@@ -0,0 +1,939 @@
+// Package h provides go.h components which reference files using the hashed
+// URLs provided by the Resolver in the context. Components do not modify
+// themselves while rendering, so a shared instance may be rendered by many
+// goroutines at once.
+package h
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+
+	"github.com/daaku/go.h"
+	"github.com/daaku/go.static"
+)
+
+// SRI overrides the subresource integrity attributes of a component, which
+// are otherwise added when the Resolver provides them.
+type SRI struct {
+	Integrity   string // Used instead of the provided integrity if set.
+	CrossOrigin string // Used instead of "anonymous" if set.
+	Disabled    bool   // Omits the attributes.
+}
+
+// integrityAttributes adds the integrity and crossorigin attributes for the
+// given names, if the Resolver provides them or they are set in sri.
+func integrityAttributes(ctx context.Context, attrs h.Attributes, names []string, sri SRI) error {
+	if sri.Disabled {
+		return nil
+	}
+	integrity := sri.Integrity
+	if integrity == "" {
+		var err error
+		if integrity, err = static.Integrity(ctx, names...); err != nil {
+			return err
+		}
+	}
+	if integrity != "" {
+		attrs["integrity"] = integrity
+		attrs["crossorigin"] = "anonymous"
+		if sri.CrossOrigin != "" {
+			attrs["crossorigin"] = sri.CrossOrigin
+		}
+	}
+	return nil
+}
+
+// LinkStyle provides a stylesheet <link> where the HREFs are combined and
+// served using the Resolver in the context.
+type LinkStyle struct {
+	HREF  []string
+	Media string // Optional media query, such as "print".
+	SRI   SRI
+	Attrs h.Attributes // Additional attributes, overriding generated ones.
+}
+
+// HTML returns the <link> tag with the appropriate attributes.
+func (l *LinkStyle) HTML(ctx context.Context) (h.HTML, error) {
+	url, err := static.URL(ctx, l.HREF...)
+	if err != nil {
+		return nil, err
+	}
+	attrs := h.Attributes{
+		"rel":  "stylesheet",
+		"href": url,
+	}
+	if l.Media != "" {
+		attrs["media"] = l.Media
+	}
+	if err := integrityAttributes(ctx, attrs, l.HREF, l.SRI); err != nil {
+		return nil, err
+	}
+	mergeAttributes(attrs, l.Attrs)
+	return &h.Node{
+		Tag:         "link",
+		Attributes:  attrs,
+		SelfClosing: true,
+	}, nil
+}
+
+// defaultInlineSize is the default MaxSize for inlined files.
+const defaultInlineSize = 4096
+
+// InlineStyle provides a <style> with the combined contents of the HREFs if
+// they are no larger than MaxSize, such as for critical CSS, and otherwise a
+// LinkStyle.
+type InlineStyle struct {
+	HREF    []string
+	Media   string
+	Nonce   string              // Added if inlined, defaults to the one in the context.
+	MaxSize int                 // Defaults to 4KB.
+	Minify  func([]byte) []byte // Optional minifier for inlined contents.
+}
+
+// HTML returns the <style> or <link> tag.
+func (s *InlineStyle) HTML(ctx context.Context) (h.HTML, error) {
+	contents, err := inlineContents(ctx, s.HREF, s.MaxSize, s.Minify, "</style")
+	if err != nil {
+		return nil, err
+	}
+	if contents == nil {
+		l := LinkStyle{HREF: s.HREF, Media: s.Media}
+		return l.HTML(ctx)
+	}
+	attrs := h.Attributes{}
+	addAttributes(attrs, "media", s.Media, "nonce", nonce(ctx, s.Nonce))
+	return &h.Node{
+		Tag:        "style",
+		Attributes: attrs,
+		Inner:      h.Unsafe(contents),
+	}, nil
+}
+
+// inlineContents returns the combined contents of the names if the Resolver
+// provides them and they fit within maxSize, or nil. Contents including the
+// closing tag are never inlined, as they would end the element early.
+func inlineContents(ctx context.Context, names []string, maxSize int, minify func([]byte) []byte, closing string) ([]byte, error) {
+	contents, err := static.Content(ctx, names...)
+	if err != nil || contents == nil {
+		return nil, err
+	}
+	if minify != nil {
+		contents = minify(contents)
+	}
+	if maxSize == 0 {
+		maxSize = defaultInlineSize
+	}
+	if len(contents) > maxSize || bytes.Contains(bytes.ToLower(contents), []byte(closing)) {
+		return nil, nil
+	}
+	return contents, nil
+}
+
+// Script provides a <script> where the Srcs are combined and served using the
+// Resolver in the context.
+type Script struct {
+	Src   []string
+	Async bool
+	Defer bool
+	SRI   SRI
+	Nonce string       // Defaults to the nonce in the context.
+	Attrs h.Attributes // Additional attributes, overriding generated ones.
+}
+
+// HTML returns the <script> tag with the appropriate attributes.
+func (l *Script) HTML(ctx context.Context) (h.HTML, error) {
+	url, err := static.URL(ctx, l.Src...)
+	if err != nil {
+		return nil, err
+	}
+	attrs := h.Attributes{
+		"src":   url,
+		"async": l.Async,
+		"defer": l.Defer,
+	}
+	addAttributes(attrs, "nonce", nonce(ctx, l.Nonce))
+	if err := integrityAttributes(ctx, attrs, l.Src, l.SRI); err != nil {
+		return nil, err
+	}
+	mergeAttributes(attrs, l.Attrs)
+	return &h.Node{
+		Tag:        "script",
+		Attributes: attrs,
+	}, nil
+}
+
+// InlineScript provides a <script> with the combined contents of the Srcs if
+// they are no larger than MaxSize, such as for small bootstrap scripts, and
+// otherwise a Script. The Nonce, or the one in the context, is included in
+// either case to allow it under a Content Security Policy, or CSPHash can be
+// used in the script-src instead.
+type InlineScript struct {
+	Src     []string
+	Nonce   string
+	MaxSize int                 // Defaults to 4KB.
+	Minify  func([]byte) []byte // Optional minifier for inlined contents.
+}
+
+// HTML returns the <script> tag with either the contents or the src.
+func (s *InlineScript) HTML(ctx context.Context) (h.HTML, error) {
+	contents, err := inlineContents(ctx, s.Src, s.MaxSize, s.Minify, "</script")
+	if err != nil {
+		return nil, err
+	}
+	if contents == nil {
+		l := Script{Src: s.Src, Nonce: s.Nonce}
+		return l.HTML(ctx)
+	}
+	attrs := h.Attributes{}
+	addAttributes(attrs, "nonce", nonce(ctx, s.Nonce))
+	return &h.Node{
+		Tag:        "script",
+		Attributes: attrs,
+		Inner:      h.Unsafe(contents),
+	}, nil
+}
+
+// CSPHash returns the Content Security Policy source expression allowing the
+// inlined script, such as 'sha256-...', or an empty string if it would not be
+// inlined.
+func (s *InlineScript) CSPHash(ctx context.Context) (string, error) {
+	contents, err := inlineContents(ctx, s.Src, s.MaxSize, s.Minify, "</script")
+	if err != nil || contents == nil {
+		return "", err
+	}
+	sum := sha256.Sum256(contents)
+	return "'sha256-" + base64.StdEncoding.EncodeToString(sum[:]) + "'", nil
+}
+
+// ScriptBundle provides a single Script combining the Srcs, or one Script per
+// Src when Debug is set or the Resolver is in development mode, for readable
+// stack traces and devtools. The SRI, Nonce and Attrs apply to each Script,
+// and a set SRI.Integrity is only used for the combined one, as each Src has
+// its own.
+type ScriptBundle struct {
+	Src   []string
+	Async bool
+	Defer bool
+	Debug bool
+	SRI   SRI
+	Nonce string       // Defaults to the nonce in the context.
+	Attrs h.Attributes // Additional attributes, overriding generated ones.
+}
+
+// HTML returns the <script> tags.
+func (b *ScriptBundle) HTML(ctx context.Context) (h.HTML, error) {
+	if !b.Debug && !static.DevMode(ctx) {
+		s := Script{Src: b.Src, Async: b.Async, Defer: b.Defer, SRI: b.SRI, Nonce: b.Nonce, Attrs: b.Attrs}
+		return s.HTML(ctx)
+	}
+	sri := b.SRI
+	sri.Integrity = ""
+	frag := make(h.Frag, 0, len(b.Src))
+	for _, src := range b.Src {
+		frag = append(frag, &Script{Src: []string{src}, Async: b.Async, Defer: b.Defer, SRI: sri, Nonce: b.Nonce, Attrs: b.Attrs})
+	}
+	return frag, nil
+}
+
+// StyleBundle provides a single LinkStyle combining the HREFs, or one
+// LinkStyle per HREF when Debug is set or the Resolver is in development mode,
+// so devtools show the original files. The SRI and Attrs apply as they do for
+// a ScriptBundle.
+type StyleBundle struct {
+	HREF  []string
+	Media string
+	Debug bool
+	SRI   SRI
+	Attrs h.Attributes // Additional attributes, overriding generated ones.
+}
+
+// HTML returns the <link> tags.
+func (b *StyleBundle) HTML(ctx context.Context) (h.HTML, error) {
+	if !b.Debug && !static.DevMode(ctx) {
+		l := LinkStyle{HREF: b.HREF, Media: b.Media, SRI: b.SRI, Attrs: b.Attrs}
+		return l.HTML(ctx)
+	}
+	sri := b.SRI
+	sri.Integrity = ""
+	frag := make(h.Frag, 0, len(b.HREF))
+	for _, href := range b.HREF {
+		frag = append(frag, &LinkStyle{HREF: []string{href}, Media: b.Media, SRI: sri, Attrs: b.Attrs})
+	}
+	return frag, nil
+}
+
+// ModuleScript provides a <script type="module"> for browsers supporting
+// JavaScript modules, and a <script nomodule> for older browsers, where each of
+// Module and NoModule are combined and served using the Resolver in the
+// context.
+type ModuleScript struct {
+	Module   []string
+	NoModule []string
+}
+
+// HTML returns the <script> tags with the appropriate attributes. The nomodule
+// tag is omitted if there is no NoModule.
+func (m *ModuleScript) HTML(ctx context.Context) (h.HTML, error) {
+	module, err := m.script(ctx, m.Module, "type", "module")
+	if err != nil {
+		return nil, err
+	}
+	if len(m.NoModule) == 0 {
+		return module, nil
+	}
+	nomodule, err := m.script(ctx, m.NoModule, "nomodule", true)
+	if err != nil {
+		return nil, err
+	}
+	return h.Frag{module, nomodule}, nil
+}
+
+func (m *ModuleScript) script(ctx context.Context, src []string, key string, value interface{}) (*h.Node, error) {
+	s := Script{Src: src}
+	v, err := s.HTML(ctx)
+	if err != nil {
+		return nil, err
+	}
+	n := v.(*h.Node)
+	delete(n.Attributes, "async")
+	delete(n.Attributes, "defer")
+	n.Attributes[key] = value
+	return n, nil
+}
+
+// ImportMap provides a <script type="importmap"> mapping bare module
+// specifiers to the hashed URLs of files, using the Resolver in the context,
+// so modules can import each other by name while remaining cacheable.
+type ImportMap struct {
+	Imports map[string]string // Module specifier to file name.
+	Nonce   string            // Defaults to the nonce in the context.
+}
+
+// HTML returns the <script> tag with the JSON import map.
+func (m *ImportMap) HTML(ctx context.Context) (h.HTML, error) {
+	imports := make(map[string]string, len(m.Imports))
+	for specifier, name := range m.Imports {
+		url, err := static.URL(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		imports[specifier] = url
+	}
+	contents, err := json.Marshal(struct {
+		Imports map[string]string `json:"imports"`
+	}{imports})
+	if err != nil {
+		return nil, err
+	}
+	attrs := h.Attributes{"type": "importmap"}
+	addAttributes(attrs, "nonce", nonce(ctx, m.Nonce))
+	return &h.Node{
+		Tag:        "script",
+		Attributes: attrs,
+		Inner:      h.Unsafe(contents),
+	}, nil
+}
+
+// addAttributes adds the non empty values to attrs.
+func addAttributes(attrs h.Attributes, values ...string) {
+	for i := 0; i+1 < len(values); i += 2 {
+		if values[i+1] != "" {
+			attrs[values[i]] = values[i+1]
+		}
+	}
+}
+
+// mergeAttributes sets the extra attributes on attrs.
+func mergeAttributes(attrs, extra h.Attributes) {
+	for k, v := range extra {
+		attrs[k] = v
+	}
+}
+
+// ImgSource is a candidate in the srcset of an Img.
+type ImgSource struct {
+	Src        string
+	Descriptor string // Width or density descriptor, such as "640w" or "2x".
+
+	// Width resizes the Src to this width, using the Resolver in the context.
+	// The Descriptor defaults to the width, such as "640w".
+	Width int
+}
+
+// Img provides an <img> where the Src and SrcSet are served using the Resolver
+// in the context.
+type Img struct {
+	ID     string
+	Class  string
+	Style  string
+	Src    string
+	Alt    string
+	SrcSet []ImgSource
+	Sizes  string // Optional sizes for width descriptors in SrcSet.
+
+	Loading  string // Optional loading, "lazy" or "eager".
+	Decoding string // Optional decoding, "async", "sync" or "auto".
+
+	// InlineSize embeds the Src as a data URI if it is no larger than this
+	// many bytes, saving a request for tiny images such as icons.
+	InlineSize int
+
+	// NoDimensions omits the width and height attributes, which are otherwise
+	// set from the image if the Resolver provides its size, so the layout does
+	// not shift while it loads.
+	NoDimensions bool
+
+	// Attrs are additional attributes, such as fetchpriority or data-*, which
+	// override generated ones.
+	Attrs h.Attributes
+}
+
+// HTML returns the <img> tag with the appropriate attributes.
+func (i *Img) HTML(ctx context.Context) (h.HTML, error) {
+	var src string
+	if i.InlineSize > 0 {
+		var err error
+		if src, err = static.DataURI(ctx, i.Src, i.InlineSize); err != nil {
+			return nil, err
+		}
+	}
+	if src == "" {
+		var err error
+		if src, err = static.URL(ctx, i.Src); err != nil {
+			return nil, err
+		}
+	}
+	srcset, err := srcSet(ctx, i.SrcSet)
+	if err != nil {
+		return nil, err
+	}
+	attrs := h.Attributes{"src": src}
+	if !i.NoDimensions {
+		width, height, err := static.ImageSize(ctx, i.Src)
+		if err != nil {
+			return nil, err
+		}
+		if width > 0 && height > 0 {
+			attrs["width"] = strconv.Itoa(width)
+			attrs["height"] = strconv.Itoa(height)
+		}
+	}
+	addAttributes(attrs,
+		"id", i.ID,
+		"class", i.Class,
+		"style", i.Style,
+		"alt", i.Alt,
+		"srcset", srcset,
+		"sizes", i.Sizes,
+		"loading", i.Loading,
+		"decoding", i.Decoding,
+	)
+	mergeAttributes(attrs, i.Attrs)
+	return &h.Node{
+		Tag:         "img",
+		Attributes:  attrs,
+		SelfClosing: true,
+	}, nil
+}
+
+// srcSet returns the srcset attribute value for the sources.
+func srcSet(ctx context.Context, sources []ImgSource) (string, error) {
+	candidates := make([]string, 0, len(sources))
+	for _, source := range sources {
+		var url string
+		var err error
+		descriptor := source.Descriptor
+		if source.Width > 0 {
+			url, err = static.ResizedURL(ctx, source.Src, source.Width)
+			if descriptor == "" {
+				descriptor = strconv.Itoa(source.Width) + "w"
+			}
+		} else {
+			url, err = static.URL(ctx, source.Src)
+		}
+		if err != nil {
+			return "", err
+		}
+		if descriptor != "" {
+			url += " " + descriptor
+		}
+		candidates = append(candidates, url)
+	}
+	return strings.Join(candidates, ", "), nil
+}
+
+// PictureSource is a <source> in a Picture, which browsers use if they
+// support its Type.
+type PictureSource struct {
+	Type   string // Image type, such as "image/avif" or "image/webp".
+	SrcSet []ImgSource
+	Sizes  string
+	Media  string
+}
+
+// Picture provides a <picture> with the Sources in order of preference,
+// followed by the fallback Img, all served using the Resolver in the context.
+type Picture struct {
+	Sources []PictureSource
+	Img     Img
+}
+
+// HTML returns the <picture> tag with its <source> and <img> tags.
+func (p *Picture) HTML(ctx context.Context) (h.HTML, error) {
+	inner := make(h.Frag, 0, len(p.Sources)+1)
+	for _, source := range p.Sources {
+		srcset, err := srcSet(ctx, source.SrcSet)
+		if err != nil {
+			return nil, err
+		}
+		attrs := h.Attributes{"srcset": srcset}
+		addAttributes(attrs,
+			"type", source.Type,
+			"sizes", source.Sizes,
+			"media", source.Media,
+		)
+		inner = append(inner, &h.Node{
+			Tag:         "source",
+			Attributes:  attrs,
+			SelfClosing: true,
+		})
+	}
+	img, err := p.Img.HTML(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &h.Node{
+		Tag:   "picture",
+		Inner: append(inner, img),
+	}, nil
+}
+
+// Favicon provides a h.Link for a favicon.
+type Favicon struct {
+	HREF string
+}
+
+// HTML returns the <script> tag with the appropriate attributes.
+func (l *Favicon) HTML(ctx context.Context) (h.HTML, error) {
+	url, err := static.URL(ctx, l.HREF)
+	if err != nil {
+		return nil, err
+	}
+	return &h.Link{
+		Rel:  "shortcut icon",
+		HREF: url,
+	}, nil
+}
+
+// Icons provides the standard set of icon <link> tags for PNG icons named by
+// size from Base, such as "img/icon-32x32.png" for the Base "img/icon", served
+// using the Resolver in the context. Use static.Handler.FileHandler to serve
+// "/favicon.ico" for browsers which request it directly.
+type Icons struct {
+	Base       string
+	Sizes      []int // Sizes of the rel="icon" images, such as 16 and 32.
+	AppleTouch int   // Size of the apple-touch-icon image, such as 180.
+}
+
+func (i *Icons) link(ctx context.Context, rel string, size int) (h.HTML, error) {
+	sizes := fmt.Sprintf("%dx%d", size, size)
+	url, err := static.URL(ctx, i.Base+"-"+sizes+".png")
+	if err != nil {
+		return nil, err
+	}
+	attrs := h.Attributes{
+		"rel":   rel,
+		"sizes": sizes,
+		"href":  url,
+	}
+	if rel == "icon" {
+		attrs["type"] = "image/png"
+	}
+	return &h.Node{
+		Tag:         "link",
+		Attributes:  attrs,
+		SelfClosing: true,
+	}, nil
+}
+
+// HTML returns the <link> tags for the icons.
+func (i *Icons) HTML(ctx context.Context) (h.HTML, error) {
+	links := make(h.Frag, 0, len(i.Sizes)+1)
+	for _, size := range i.Sizes {
+		link, err := i.link(ctx, "icon", size)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	if i.AppleTouch > 0 {
+		link, err := i.link(ctx, "apple-touch-icon", i.AppleTouch)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	return links, nil
+}
+
+// WebManifest provides a <link rel="manifest"> for the Web App Manifest in the
+// named .webmanifest file, served using the Resolver in the context with the
+// src of its icons replaced by hashed URLs.
+type WebManifest struct {
+	HREF string
+}
+
+// HTML returns the <link> tag.
+func (m *WebManifest) HTML(ctx context.Context) (h.HTML, error) {
+	url, err := static.URL(ctx, m.HREF)
+	if err != nil {
+		return nil, err
+	}
+	return &h.Node{
+		Tag: "link",
+		Attributes: h.Attributes{
+			"rel":  "manifest",
+			"href": url,
+		},
+		SelfClosing: true,
+	}, nil
+}
+
+// BackgroundImage returns an inline style value, for use in a style
+// attribute, setting the background image to the hashed URL of name.
+func BackgroundImage(ctx context.Context, name string) (string, error) {
+	url, err := static.URL(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("background-image:url(%q)", url), nil
+}
+
+// BackgroundClass provides a <style> defining a class which sets the
+// background image to the hashed URL of Src, for pages which avoid inline
+// style attributes.
+type BackgroundClass struct {
+	Class string
+	Src   string
+	Nonce string // Defaults to the nonce in the context.
+}
+
+// HTML returns the <style> tag. It fails if Class is not a valid CSS
+// identifier, as it is written into the stylesheet without escaping.
+func (b *BackgroundClass) HTML(ctx context.Context) (h.HTML, error) {
+	if !cssIdent(b.Class) {
+		return nil, fmt.Errorf("h: invalid class name %q", b.Class)
+	}
+	style, err := BackgroundImage(ctx, b.Src)
+	if err != nil {
+		return nil, err
+	}
+	attrs := h.Attributes{}
+	addAttributes(attrs, "nonce", nonce(ctx, b.Nonce))
+	return &h.Node{
+		Tag:        "style",
+		Attributes: attrs,
+		Inner:      h.Unsafe("." + b.Class + "{" + style + "}"),
+	}, nil
+}
+
+// cssIdent reports if s is a CSS identifier which needs no escaping, made of
+// ASCII letters, digits, hyphens and underscores and not starting with a
+// digit or a hyphen followed by a digit.
+func cssIdent(s string) bool {
+	name := strings.TrimPrefix(s, "-")
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		return false
+	}
+	for _, c := range s {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// SocialImage provides the Open Graph and Twitter card <meta> tags for an
+// image, using the absolute URL from the Resolver in the context since social
+// scrapers require fully qualified URLs.
+type SocialImage struct {
+	Src string
+	Alt string
+}
+
+// HTML returns the <meta> tags, including the image dimensions if the Resolver
+// provides them.
+func (i *SocialImage) HTML(ctx context.Context) (h.HTML, error) {
+	url, err := static.AbsoluteURL(ctx, i.Src)
+	if err != nil {
+		return nil, err
+	}
+	width, height, err := static.ImageSize(ctx, i.Src)
+	if err != nil {
+		return nil, err
+	}
+	frag := h.Frag{meta("property", "og:image", url)}
+	if width > 0 && height > 0 {
+		frag = append(frag,
+			meta("property", "og:image:width", strconv.Itoa(width)),
+			meta("property", "og:image:height", strconv.Itoa(height)),
+		)
+	}
+	if i.Alt != "" {
+		frag = append(frag, meta("property", "og:image:alt", i.Alt))
+	}
+	frag = append(frag, meta("name", "twitter:image", url))
+	if i.Alt != "" {
+		frag = append(frag, meta("name", "twitter:image:alt", i.Alt))
+	}
+	return frag, nil
+}
+
+func meta(key, name, content string) *h.Node {
+	return &h.Node{
+		Tag: "meta",
+		Attributes: h.Attributes{
+			key:       name,
+			"content": content,
+		},
+		SelfClosing: true,
+	}
+}
+
+// InlineSVG provides the markup of an SVG file inline, so it can be styled
+// with CSS, using the contents from the Resolver in the context. The ID and
+// Class are set on the root <svg> element, replacing those in the file.
+type InlineSVG struct {
+	Src         string
+	ID          string
+	Class       string
+	StripProlog bool // Removes the XML declaration, doctype and comments before the root.
+}
+
+// HTML returns the <svg> markup.
+func (s *InlineSVG) HTML(ctx context.Context) (h.HTML, error) {
+	contents, err := static.Content(ctx, s.Src)
+	if err != nil {
+		return nil, err
+	}
+	if contents == nil {
+		return nil, fmt.Errorf("h: no contents for inline SVG %q", s.Src)
+	}
+	start, end, root, ok := svgRoot(contents)
+	if !ok {
+		return nil, fmt.Errorf("h: no <svg> element in %q", s.Src)
+	}
+	prolog := string(contents[:start])
+	if s.StripProlog {
+		prolog = ""
+	}
+	tag := string(contents[start:end])
+	if s.ID != "" || s.Class != "" {
+		tag = svgTag(root, contents[end-2] == '/', s.ID, s.Class)
+	}
+	return h.Unsafe(prolog + tag + string(contents[end:])), nil
+}
+
+// svgRoot finds the start tag of the root element using an XML tokenizer, so
+// comments, the prolog or a doctype cannot be mistaken for it. It returns the
+// offsets of the tag, and reports if it is an <svg> element.
+func svgRoot(contents []byte) (int, int, xml.StartElement, bool) {
+	dec := xml.NewDecoder(bytes.NewReader(contents))
+	dec.Strict = false
+	dec.Entity = xml.HTMLEntity
+	for {
+		start := dec.InputOffset()
+		tok, err := dec.RawToken()
+		if err != nil {
+			return 0, 0, xml.StartElement{}, false
+		}
+		if el, ok := tok.(xml.StartElement); ok {
+			return int(start), int(dec.InputOffset()), el, el.Name.Local == "svg"
+		}
+	}
+}
+
+// svgTag returns the start tag of the root element with the id and class set,
+// replacing the ones from the file if they are not empty.
+func svgTag(root xml.StartElement, selfClosing bool, id, class string) string {
+	var tag strings.Builder
+	tag.WriteString("<" + xmlName(root.Name))
+	for _, a := range [][2]string{{"id", id}, {"class", class}} {
+		if a[1] != "" {
+			fmt.Fprintf(&tag, " %s=\"%s\"", a[0], html.EscapeString(a[1]))
+		}
+	}
+	for _, a := range root.Attr {
+		name := xmlName(a.Name)
+		if (name == "id" && id != "") || (name == "class" && class != "") {
+			continue
+		}
+		fmt.Fprintf(&tag, " %s=\"%s\"", name, html.EscapeString(a.Value))
+	}
+	if selfClosing {
+		tag.WriteString("/")
+	}
+	tag.WriteString(">")
+	return tag.String()
+}
+
+// xmlName returns the name as written, including its namespace prefix.
+func xmlName(n xml.Name) string {
+	if n.Space != "" {
+		return n.Space + ":" + n.Local
+	}
+	return n.Local
+}
+
+// MediaSource is a <source> in a Video or Audio, which browsers use if they
+// support its Type.
+type MediaSource struct {
+	Src  string
+	Type string // Media type, such as "video/webm".
+}
+
+// media returns a <video> or <audio> tag with the Src and Sources served using
+// the Resolver in the context.
+func media(ctx context.Context, tag string, attrs h.Attributes, src string, sources []MediaSource, inner h.HTML) (h.HTML, error) {
+	if src != "" {
+		url, err := static.URL(ctx, src)
+		if err != nil {
+			return nil, err
+		}
+		attrs["src"] = url
+	}
+	children := make(h.Frag, 0, len(sources)+1)
+	for _, source := range sources {
+		url, err := static.URL(ctx, source.Src)
+		if err != nil {
+			return nil, err
+		}
+		sourceAttrs := h.Attributes{"src": url}
+		addAttributes(sourceAttrs, "type", source.Type)
+		children = append(children, &h.Node{
+			Tag:         "source",
+			Attributes:  sourceAttrs,
+			SelfClosing: true,
+		})
+	}
+	if inner != nil {
+		children = append(children, inner)
+	}
+	return &h.Node{
+		Tag:        tag,
+		Attributes: attrs,
+		Inner:      children,
+	}, nil
+}
+
+// Video provides a <video> where the Src, Poster and Sources are served using
+// the Resolver in the context.
+type Video struct {
+	ID          string
+	Class       string
+	Src         string
+	Poster      string
+	Sources     []MediaSource
+	Preload     string // Optional preload, "none", "metadata" or "auto".
+	Controls    bool
+	Autoplay    bool
+	Muted       bool
+	Loop        bool
+	PlaysInline bool
+	Inner       h.HTML // Fallback for browsers without video support.
+}
+
+// HTML returns the <video> tag with its <source> tags.
+func (v *Video) HTML(ctx context.Context) (h.HTML, error) {
+	attrs := h.Attributes{
+		"controls":    v.Controls,
+		"autoplay":    v.Autoplay,
+		"muted":       v.Muted,
+		"loop":        v.Loop,
+		"playsinline": v.PlaysInline,
+	}
+	addAttributes(attrs, "id", v.ID, "class", v.Class, "preload", v.Preload)
+	if v.Poster != "" {
+		poster, err := static.URL(ctx, v.Poster)
+		if err != nil {
+			return nil, err
+		}
+		attrs["poster"] = poster
+	}
+	return media(ctx, "video", attrs, v.Src, v.Sources, v.Inner)
+}
+
+// Audio provides an <audio> where the Src and Sources are served using the
+// Resolver in the context.
+type Audio struct {
+	ID       string
+	Class    string
+	Src      string
+	Sources  []MediaSource
+	Preload  string // Optional preload, "none", "metadata" or "auto".
+	Controls bool
+	Autoplay bool
+	Muted    bool
+	Loop     bool
+	Inner    h.HTML // Fallback for browsers without audio support.
+}
+
+// HTML returns the <audio> tag with its <source> tags.
+func (a *Audio) HTML(ctx context.Context) (h.HTML, error) {
+	attrs := h.Attributes{
+		"controls": a.Controls,
+		"autoplay": a.Autoplay,
+		"muted":    a.Muted,
+		"loop":     a.Loop,
+	}
+	addAttributes(attrs, "id", a.ID, "class", a.Class, "preload", a.Preload)
+	return media(ctx, "audio", attrs, a.Src, a.Sources, a.Inner)
+}
+
+// Input renders a HTML <input> tag with the Src URL transformed.
+type Input struct {
+	ID          string
+	Class       string
+	Name        string
+	Style       string
+	Type        string
+	Value       string
+	Src         string
+	Placeholder string
+	Checked     bool
+	Multiple    bool
+	Data        map[string]interface{}
+	Inner       h.HTML
+}
+
+// HTML renders the content.
+func (i *Input) HTML(ctx context.Context) (h.HTML, error) {
+	src, err := static.URL(ctx, i.Src)
+	if err != nil {
+		return nil, err
+	}
+	return &h.Input{
+		ID:          i.ID,
+		Class:       i.Class,
+		Name:        i.Name,
+		Style:       i.Style,
+		Type:        i.Type,
+		Value:       i.Value,
+		Src:         src,
+		Placeholder: i.Placeholder,
+		Checked:     i.Checked,
+		Multiple:    i.Multiple,
+		Data:        i.Data,
+		Inner:       i.Inner,
+	}, nil
+}
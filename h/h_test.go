@@ -0,0 +1,1053 @@
+package h
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/daaku/go.h"
+	"github.com/daaku/go.static"
+	"github.com/facebookgo/ensure"
+)
+
+const fooIntegrity = "sha384-mMEf/f3VQGdrGhN8saIrKnA1DJpEFx1rEYDGvly7LuP3nVMsih3Z7y6OCOdSo7q7"
+
+type funcBox func(name string) ([]byte, error)
+
+func (f funcBox) Bytes(name string) ([]byte, error) {
+	return f(name)
+}
+
+func makeCtx(h *static.Handler) context.Context {
+	return static.NewContext(context.Background(), h)
+}
+
+func TestLinkStyleInvalidHREF(t *testing.T) {
+	givenErr := errors.New("")
+	ctx := makeCtx(&static.Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			return nil, givenErr
+		}),
+	})
+	l := LinkStyle{
+		HREF: []string{"foo"},
+	}
+	v, err := l.HTML(ctx)
+	ensure.Nil(t, v)
+	ensure.DeepEqual(t, err, givenErr)
+}
+
+func TestLinkStyle(t *testing.T) {
+	ctx := makeCtx(&static.Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	})
+	l := LinkStyle{
+		HREF: []string{"foo"},
+	}
+	v, err := l.HTML(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, &h.Node{
+		Tag: "link",
+		Attributes: h.Attributes{
+			"rel":  "stylesheet",
+			"href": "W1siZm9vIiwiYWNiZDE4ZGIiXV0",
+		},
+		SelfClosing: true,
+	})
+}
+
+func TestLinkStyleMedia(t *testing.T) {
+	ctx := makeCtx(&static.Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	})
+	l := LinkStyle{
+		HREF:  []string{"foo"},
+		Media: "print",
+	}
+	v, err := l.HTML(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, &h.Node{
+		Tag: "link",
+		Attributes: h.Attributes{
+			"rel":   "stylesheet",
+			"href":  "W1siZm9vIiwiYWNiZDE4ZGIiXV0",
+			"media": "print",
+		},
+		SelfClosing: true,
+	})
+}
+
+func TestInlineStyle(t *testing.T) {
+	contents := map[string]string{"a.css": "a { }", "big.css": strings.Repeat("x", 5000)}
+	ctx := makeCtx(&static.Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte(contents[name]), nil
+		}),
+	})
+	s := InlineStyle{
+		HREF:   []string{"a.css", "a.css"},
+		Media:  "print",
+		Minify: func(b []byte) []byte { return bytes.ReplaceAll(b, []byte(" "), nil) },
+	}
+	v, err := s.HTML(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, &h.Node{
+		Tag:        "style",
+		Attributes: h.Attributes{"media": "print"},
+		Inner:      h.Unsafe("a{}"),
+	})
+
+	s = InlineStyle{HREF: []string{"big.css"}}
+	v, err = s.HTML(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v.(*h.Node).Tag, "link")
+
+	s = InlineStyle{HREF: []string{"big.css"}, MaxSize: 10000}
+	v, err = s.HTML(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v.(*h.Node).Tag, "style")
+}
+
+func TestInlineStyleClosingTag(t *testing.T) {
+	ctx := makeCtx(&static.Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("a{content:'</STYLE>'}"), nil
+		}),
+	})
+	s := InlineStyle{HREF: []string{"a.css"}}
+	v, err := s.HTML(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v.(*h.Node).Tag, "link")
+}
+
+func TestInlineScript(t *testing.T) {
+	contents := map[string]string{"a.js": "go()", "big.js": strings.Repeat("x", 5000)}
+	ctx := makeCtx(&static.Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte(contents[name]), nil
+		}),
+	})
+	s := InlineScript{Src: []string{"a.js"}, Nonce: "n"}
+	v, err := s.HTML(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, &h.Node{
+		Tag:        "script",
+		Attributes: h.Attributes{"nonce": "n"},
+		Inner:      h.Unsafe("go()"),
+	})
+	hash, err := s.CSPHash(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, hash, "'sha256-5KYv+PUboo5h+0+YAtGRPbwv5d/QxzHslP4YGnUaxRw='")
+
+	s = InlineScript{Src: []string{"big.js"}, Nonce: "n"}
+	v, err = s.HTML(ctx)
+	ensure.Nil(t, err)
+	n := v.(*h.Node)
+	ensure.DeepEqual(t, n.Inner, nil)
+	ensure.DeepEqual(t, n.Attributes["nonce"], "n")
+	ensure.NotDeepEqual(t, n.Attributes["src"], nil)
+	hash, err = s.CSPHash(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, hash, "")
+}
+
+func TestSocialImage(t *testing.T) {
+	ctx := makeCtx(&static.Handler{
+		Path:    "/static/",
+		BaseURL: "https://example.com",
+		Box: funcBox(func(name string) ([]byte, error) {
+			var buf bytes.Buffer
+			err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 4, 2)))
+			return buf.Bytes(), err
+		}),
+	})
+	i := SocialImage{Src: "card.png", Alt: "A card"}
+	v, err := i.HTML(ctx)
+	ensure.Nil(t, err)
+	frag := v.(h.Frag)
+	ensure.DeepEqual(t, len(frag), 6)
+	url := frag[0].(*h.Node).Attributes["content"].(string)
+	ensure.StringContains(t, url, "https://example.com/static/")
+	ensure.DeepEqual(t, frag[1], meta("property", "og:image:width", "4"))
+	ensure.DeepEqual(t, frag[2], meta("property", "og:image:height", "2"))
+	ensure.DeepEqual(t, frag[3], meta("property", "og:image:alt", "A card"))
+	ensure.DeepEqual(t, frag[4], meta("name", "twitter:image", url))
+	ensure.DeepEqual(t, frag[5], meta("name", "twitter:image:alt", "A card"))
+}
+
+func TestSocialImageNoBaseURL(t *testing.T) {
+	ctx := makeCtx(&static.Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	})
+	i := SocialImage{Src: "card.png"}
+	_, err := i.HTML(ctx)
+	ensure.Err(t, err, regexp.MustCompile("no base URL"))
+}
+
+func TestInlineSVG(t *testing.T) {
+	ctx := makeCtx(&static.Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte(`<?xml version="1.0"?>
+<!DOCTYPE svg PUBLIC "-//W3C//DTD SVG 1.1//EN" "svg11.dtd">
+<svg viewBox="0 0 1 1"><path d="M0 0"/></svg>`), nil
+		}),
+	})
+	s := InlineSVG{Src: "icon.svg", ID: "i", Class: `a"b`, StripProlog: true}
+	v, err := s.HTML(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, h.Unsafe(
+		`<svg id="i" class="a&#34;b" viewBox="0 0 1 1"><path d="M0 0"/></svg>`))
+
+	s = InlineSVG{Src: "icon.svg"}
+	v, err = s.HTML(ctx)
+	ensure.Nil(t, err)
+	ensure.StringContains(t, string(v.(h.Unsafe)), "<?xml")
+}
+
+func TestInlineSVGRoot(t *testing.T) {
+	ctx := makeCtx(&static.Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte(`<?xml version="1.0"?><!-- <svg> icon -->
+<svg xmlns:xlink="http://www.w3.org/1999/xlink" id="file" class="icon" width="1"/>`), nil
+		}),
+	})
+	s := InlineSVG{Src: "icon.svg", Class: "big"}
+	v, err := s.HTML(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, h.Unsafe(`<?xml version="1.0"?><!-- <svg> icon -->
+<svg class="big" xmlns:xlink="http://www.w3.org/1999/xlink" id="file" width="1"/>`))
+
+	s = InlineSVG{Src: "icon.svg", ID: "i", StripProlog: true}
+	v, err = s.HTML(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, h.Unsafe(
+		`<svg id="i" xmlns:xlink="http://www.w3.org/1999/xlink" class="icon" width="1"/>`))
+}
+
+func TestInlineSVGErrors(t *testing.T) {
+	ctx := makeCtx(&static.Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("<p>"), nil
+		}),
+	})
+	s := InlineSVG{Src: "icon.svg"}
+	_, err := s.HTML(ctx)
+	ensure.Err(t, err, regexp.MustCompile("no <svg> element"))
+
+	_, err = s.HTML(static.NewContext(context.Background(), nil))
+	ensure.Err(t, err, regexp.MustCompile("no contents for inline SVG"))
+}
+
+func TestImgInlineSize(t *testing.T) {
+	ctx := makeCtx(&static.Handler{
+		Path: "/",
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	})
+	i := Img{Src: "a.svg", InlineSize: 3, NoDimensions: true}
+	v, err := i.HTML(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v.(*h.Node).Attributes["src"], "data:image/svg+xml;base64,Zm9v")
+
+	i.InlineSize = 2
+	v, err = i.HTML(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v.(*h.Node).Attributes["src"], "/W1siYS5zdmciLCJhY2JkMThkYiJdXQ.svg")
+}
+
+func TestBackgroundImage(t *testing.T) {
+	ctx := makeCtx(&static.Handler{
+		Path: "/",
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	})
+	v, err := BackgroundImage(ctx, "a.png")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, `background-image:url("/W1siYS5wbmciLCJhY2JkMThkYiJdXQ.png")`)
+
+	b := BackgroundClass{Class: "hero", Src: "a.png"}
+	n, err := b.HTML(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, n, &h.Node{
+		Tag:        "style",
+		Attributes: h.Attributes{},
+		Inner:      h.Unsafe(`.hero{background-image:url("/W1siYS5wbmciLCJhY2JkMThkYiJdXQ.png")}`),
+	})
+}
+
+func TestBackgroundImageError(t *testing.T) {
+	givenErr := errors.New("")
+	ctx := makeCtx(&static.Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			return nil, givenErr
+		}),
+	})
+	b := BackgroundClass{Class: "hero", Src: "a.png"}
+	_, err := b.HTML(ctx)
+	ensure.DeepEqual(t, err, givenErr)
+}
+
+func TestBackgroundClassInvalid(t *testing.T) {
+	ctx := makeCtx(&static.Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			panic("not reached")
+		}),
+	})
+	for _, class := range []string{"", "-", "1a", "-1a", "a}body{display:none", "a</style>"} {
+		b := BackgroundClass{Class: class, Src: "a.png"}
+		_, err := b.HTML(ctx)
+		ensure.Err(t, err, regexp.MustCompile("invalid class name"))
+	}
+	ensure.True(t, cssIdent("-hero_2"))
+	ensure.True(t, cssIdent("_a"))
+}
+
+func TestImportMap(t *testing.T) {
+	ctx := makeCtx(&static.Handler{
+		Path: "/",
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	})
+	m := ImportMap{
+		Imports: map[string]string{"app": "app.mjs", "lib": "lib.mjs"},
+		Nonce:   "n",
+	}
+	v, err := m.HTML(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, &h.Node{
+		Tag:        "script",
+		Attributes: h.Attributes{"type": "importmap", "nonce": "n"},
+		Inner: h.Unsafe(`{"imports":{` +
+			`"app":"/W1siYXBwLm1qcyIsImFjYmQxOGRiIl1d.mjs",` +
+			`"lib":"/W1sibGliLm1qcyIsImFjYmQxOGRiIl1d.mjs"}}`),
+	})
+}
+
+func TestImportMapError(t *testing.T) {
+	givenErr := errors.New("")
+	ctx := makeCtx(&static.Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			return nil, givenErr
+		}),
+	})
+	m := ImportMap{Imports: map[string]string{"app": "app.mjs"}}
+	_, err := m.HTML(ctx)
+	ensure.DeepEqual(t, err, givenErr)
+}
+
+func TestConcurrentRender(t *testing.T) {
+	ctx := makeCtx(&static.Handler{
+		SRI: true,
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	})
+	components := []h.HTML{
+		&LinkStyle{HREF: []string{"a.css"}},
+		&Script{Src: []string{"a.js"}},
+		&ModuleScript{Module: []string{"a.mjs"}, NoModule: []string{"a.js"}},
+		&Img{Src: "a.png", SrcSet: []ImgSource{{Src: "b.png", Descriptor: "2x"}}},
+		&InlineScript{Src: []string{"a.js"}},
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, c := range components {
+				if _, err := c.HTML(ctx); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestAttrs(t *testing.T) {
+	ctx := makeCtx(&static.Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	})
+	attrs := h.Attributes{"data-x": "1", "fetchpriority": "high"}
+	for _, c := range []h.HTML{
+		&LinkStyle{HREF: []string{"a.css"}, Attrs: attrs},
+		&Script{Src: []string{"a.js"}, Attrs: attrs},
+		&Img{Src: "a.png", Attrs: attrs},
+	} {
+		v, err := c.HTML(ctx)
+		ensure.Nil(t, err)
+		n := v.(*h.Node).Attributes
+		ensure.DeepEqual(t, n["data-x"], "1")
+		ensure.DeepEqual(t, n["fetchpriority"], "high")
+	}
+	ensure.DeepEqual(t, len(attrs), 2)
+}
+
+func TestScriptBundle(t *testing.T) {
+	ctx := makeCtx(&static.Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	})
+	b := ScriptBundle{Src: []string{"a.js", "b.js"}, Defer: true}
+	v, err := b.HTML(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v.(*h.Node).Tag, "script")
+
+	b.Debug = true
+	v, err = b.HTML(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, h.Frag{
+		&Script{Src: []string{"a.js"}, Defer: true},
+		&Script{Src: []string{"b.js"}, Defer: true},
+	})
+}
+
+func TestScriptBundleDevMode(t *testing.T) {
+	ctx := makeCtx(&static.Handler{Dev: true})
+	b := ScriptBundle{Src: []string{"a.js", "b.js"}}
+	v, err := b.HTML(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, len(v.(h.Frag)), 2)
+}
+
+func TestStyleBundle(t *testing.T) {
+	ctx := makeCtx(&static.Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	})
+	b := StyleBundle{HREF: []string{"a.css", "b.css"}, Media: "print"}
+	v, err := b.HTML(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v.(*h.Node).Tag, "link")
+
+	b.Debug = true
+	v, err = b.HTML(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, h.Frag{
+		&LinkStyle{HREF: []string{"a.css"}, Media: "print"},
+		&LinkStyle{HREF: []string{"b.css"}, Media: "print"},
+	})
+}
+
+func TestBundleAttributes(t *testing.T) {
+	ctx := makeCtx(&static.Handler{
+		SRI: true,
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	})
+	sri := SRI{Integrity: "sha256-given", CrossOrigin: "use-credentials"}
+	b := ScriptBundle{Src: []string{"a.js", "b.js"}, SRI: sri, Nonce: "n", Attrs: h.Attributes{"data-x": "1"}}
+	v, err := b.HTML(ctx)
+	ensure.Nil(t, err)
+	attrs := v.(*h.Node).Attributes
+	ensure.DeepEqual(t, attrs["integrity"], "sha256-given")
+	ensure.DeepEqual(t, attrs["nonce"], "n")
+	ensure.DeepEqual(t, attrs["data-x"], "1")
+
+	b.Debug = true
+	v, err = b.HTML(ctx)
+	ensure.Nil(t, err)
+	for _, member := range v.(h.Frag) {
+		m, err := member.(*Script).HTML(ctx)
+		ensure.Nil(t, err)
+		attrs := m.(*h.Node).Attributes
+		ensure.DeepEqual(t, attrs["integrity"], fooIntegrity)
+		ensure.DeepEqual(t, attrs["crossorigin"], "use-credentials")
+		ensure.DeepEqual(t, attrs["nonce"], "n")
+		ensure.DeepEqual(t, attrs["data-x"], "1")
+	}
+
+	l := StyleBundle{HREF: []string{"a.css", "b.css"}, Debug: true, SRI: sri, Attrs: h.Attributes{"data-x": "1"}}
+	v, err = l.HTML(ctx)
+	ensure.Nil(t, err)
+	for _, member := range v.(h.Frag) {
+		m, err := member.(*LinkStyle).HTML(ctx)
+		ensure.Nil(t, err)
+		attrs := m.(*h.Node).Attributes
+		ensure.DeepEqual(t, attrs["integrity"], fooIntegrity)
+		ensure.DeepEqual(t, attrs["crossorigin"], "use-credentials")
+		ensure.DeepEqual(t, attrs["data-x"], "1")
+	}
+}
+
+func TestImgSrcSetWidth(t *testing.T) {
+	ctx := makeCtx(&static.Handler{
+		Path:         "/",
+		ResizeWidths: []int{320, 640},
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	})
+	v, err := srcSet(ctx, []ImgSource{
+		{Src: "a.jpg", Width: 320},
+		{Src: "a.jpg", Width: 640, Descriptor: "2x"},
+	})
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v,
+		"/w=320/W1siYS5qcGciLCJhY2JkMThkYiJdXQ.jpg 320w, "+
+			"/w=640/W1siYS5qcGciLCJhY2JkMThkYiJdXQ.jpg 2x")
+
+	_, err = srcSet(ctx, []ImgSource{{Src: "a.jpg", Width: 100}})
+	ensure.Err(t, err, regexp.MustCompile("not one of the ResizeWidths"))
+}
+
+func TestScriptInvalidSrc(t *testing.T) {
+	givenErr := errors.New("")
+	ctx := makeCtx(&static.Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			return nil, givenErr
+		}),
+	})
+	l := Script{
+		Src: []string{"foo"},
+	}
+	v, err := l.HTML(ctx)
+	ensure.Nil(t, v)
+	ensure.DeepEqual(t, err, givenErr)
+}
+
+func TestScript(t *testing.T) {
+	ctx := makeCtx(&static.Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	})
+	l := Script{
+		Src:   []string{"foo"},
+		Async: true,
+	}
+	v, err := l.HTML(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, &h.Node{
+		Tag: "script",
+		Attributes: h.Attributes{
+			"src":   "W1siZm9vIiwiYWNiZDE4ZGIiXV0",
+			"async": true,
+			"defer": false,
+		},
+	})
+}
+
+func TestScriptDefer(t *testing.T) {
+	ctx := makeCtx(&static.Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	})
+	l := Script{
+		Src:   []string{"foo"},
+		Defer: true,
+	}
+	v, err := l.HTML(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v.(*h.Node).Attributes["defer"], true)
+	ensure.DeepEqual(t, v.(*h.Node).Attributes["async"], false)
+}
+
+func TestModuleScript(t *testing.T) {
+	ctx := makeCtx(&static.Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	})
+	m := ModuleScript{
+		Module:   []string{"foo"},
+		NoModule: []string{"foo"},
+	}
+	v, err := m.HTML(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, h.Frag{
+		&h.Node{
+			Tag: "script",
+			Attributes: h.Attributes{
+				"src":  "W1siZm9vIiwiYWNiZDE4ZGIiXV0",
+				"type": "module",
+			},
+		},
+		&h.Node{
+			Tag: "script",
+			Attributes: h.Attributes{
+				"src":      "W1siZm9vIiwiYWNiZDE4ZGIiXV0",
+				"nomodule": true,
+			},
+		},
+	})
+
+	m.NoModule = nil
+	v, err = m.HTML(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v.(*h.Node).Attributes["type"], "module")
+}
+
+func TestModuleScriptInvalidSrc(t *testing.T) {
+	givenErr := errors.New("")
+	ctx := makeCtx(&static.Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			return nil, givenErr
+		}),
+	})
+	m := ModuleScript{Module: []string{"foo"}}
+	v, err := m.HTML(ctx)
+	ensure.Nil(t, v)
+	ensure.DeepEqual(t, err, givenErr)
+}
+
+func TestLinkStyleIntegrity(t *testing.T) {
+	ctx := makeCtx(&static.Handler{
+		SRI: true,
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	})
+	l := LinkStyle{
+		HREF: []string{"foo"},
+	}
+	v, err := l.HTML(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, &h.Node{
+		Tag: "link",
+		Attributes: h.Attributes{
+			"rel":         "stylesheet",
+			"href":        "W1siZm9vIiwiYWNiZDE4ZGIiXV0",
+			"integrity":   fooIntegrity,
+			"crossorigin": "anonymous",
+		},
+		SelfClosing: true,
+	})
+}
+
+func TestScriptIntegrity(t *testing.T) {
+	ctx := makeCtx(&static.Handler{
+		SRI: true,
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	})
+	l := Script{
+		Src: []string{"foo"},
+	}
+	v, err := l.HTML(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, &h.Node{
+		Tag: "script",
+		Attributes: h.Attributes{
+			"src":         "W1siZm9vIiwiYWNiZDE4ZGIiXV0",
+			"async":       false,
+			"defer":       false,
+			"integrity":   fooIntegrity,
+			"crossorigin": "anonymous",
+		},
+	})
+}
+
+func TestIntegrityOverrides(t *testing.T) {
+	ctx := makeCtx(&static.Handler{
+		SRI: true,
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	})
+	l := Script{
+		Src: []string{"foo"},
+		SRI: SRI{Integrity: "sha256-given", CrossOrigin: "use-credentials"},
+	}
+	v, err := l.HTML(ctx)
+	ensure.Nil(t, err)
+	attrs := v.(*h.Node).Attributes
+	ensure.DeepEqual(t, attrs["integrity"], "sha256-given")
+	ensure.DeepEqual(t, attrs["crossorigin"], "use-credentials")
+
+	s := LinkStyle{
+		HREF: []string{"foo"},
+		SRI:  SRI{Disabled: true},
+	}
+	v, err = s.HTML(ctx)
+	ensure.Nil(t, err)
+	_, found := v.(*h.Node).Attributes["integrity"]
+	ensure.False(t, found)
+}
+
+func TestIntegrityGivenWithoutSRI(t *testing.T) {
+	ctx := makeCtx(&static.Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	})
+	l := LinkStyle{
+		HREF: []string{"foo"},
+		SRI:  SRI{Integrity: "sha256-given"},
+	}
+	v, err := l.HTML(ctx)
+	ensure.Nil(t, err)
+	attrs := v.(*h.Node).Attributes
+	ensure.DeepEqual(t, attrs["integrity"], "sha256-given")
+	ensure.DeepEqual(t, attrs["crossorigin"], "anonymous")
+}
+
+func TestImgInvalidSrc(t *testing.T) {
+	givenErr := errors.New("")
+	ctx := makeCtx(&static.Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			return nil, givenErr
+		}),
+	})
+	l := Img{
+		Src: "foo",
+	}
+	v, err := l.HTML(ctx)
+	ensure.Nil(t, v)
+	ensure.DeepEqual(t, err, givenErr)
+}
+
+func TestImg(t *testing.T) {
+	ctx := makeCtx(&static.Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	})
+	l := Img{
+		Src:   "foo",
+		ID:    "a",
+		Class: "b",
+		Style: "c",
+		Alt:   "d",
+	}
+	v, err := l.HTML(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, &h.Node{
+		Tag: "img",
+		Attributes: h.Attributes{
+			"src":   "W1siZm9vIiwiYWNiZDE4ZGIiXV0",
+			"id":    l.ID,
+			"class": l.Class,
+			"style": l.Style,
+			"alt":   l.Alt,
+		},
+		SelfClosing: true,
+	})
+}
+
+func TestImgSrcSet(t *testing.T) {
+	ctx := makeCtx(&static.Handler{
+		Path: "/",
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	})
+	l := Img{
+		Src: "a.png",
+		SrcSet: []ImgSource{
+			{Src: "a.png", Descriptor: "1x"},
+			{Src: "b.png", Descriptor: "2x"},
+			{Src: "c.png"},
+		},
+		Sizes: "50vw",
+	}
+	v, err := l.HTML(ctx)
+	ensure.Nil(t, err)
+	attrs := v.(*h.Node).Attributes
+	ensure.DeepEqual(t, attrs["srcset"],
+		"/W1siYS5wbmciLCJhY2JkMThkYiJdXQ.png 1x, "+
+			"/W1siYi5wbmciLCJhY2JkMThkYiJdXQ.png 2x, "+
+			"/W1siYy5wbmciLCJhY2JkMThkYiJdXQ.png")
+	ensure.DeepEqual(t, attrs["sizes"], "50vw")
+}
+
+func TestImgDimensions(t *testing.T) {
+	var buf bytes.Buffer
+	ensure.Nil(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, 3, 2))))
+	ctx := makeCtx(&static.Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			return buf.Bytes(), nil
+		}),
+	})
+	l := Img{Src: "a.png"}
+	v, err := l.HTML(ctx)
+	ensure.Nil(t, err)
+	attrs := v.(*h.Node).Attributes
+	ensure.DeepEqual(t, attrs["width"], "3")
+	ensure.DeepEqual(t, attrs["height"], "2")
+
+	l.NoDimensions = true
+	v, err = l.HTML(ctx)
+	ensure.Nil(t, err)
+	_, found := v.(*h.Node).Attributes["width"]
+	ensure.False(t, found)
+}
+
+func TestImgLoading(t *testing.T) {
+	ctx := makeCtx(&static.Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	})
+	l := Img{
+		Src:      "a.png",
+		Loading:  "lazy",
+		Decoding: "async",
+	}
+	v, err := l.HTML(ctx)
+	ensure.Nil(t, err)
+	attrs := v.(*h.Node).Attributes
+	ensure.DeepEqual(t, attrs["loading"], "lazy")
+	ensure.DeepEqual(t, attrs["decoding"], "async")
+}
+
+func TestImgInvalidSrcSet(t *testing.T) {
+	givenErr := errors.New("")
+	ctx := makeCtx(&static.Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			if name == "b.png" {
+				return nil, givenErr
+			}
+			return []byte("foo"), nil
+		}),
+	})
+	l := Img{
+		Src:    "a.png",
+		SrcSet: []ImgSource{{Src: "b.png", Descriptor: "2x"}},
+	}
+	v, err := l.HTML(ctx)
+	ensure.Nil(t, v)
+	ensure.DeepEqual(t, err, givenErr)
+}
+
+func TestPicture(t *testing.T) {
+	ctx := makeCtx(&static.Handler{
+		Path: "/",
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	})
+	p := Picture{
+		Sources: []PictureSource{
+			{Type: "image/avif", SrcSet: []ImgSource{{Src: "a.avif"}}},
+			{Type: "image/webp", SrcSet: []ImgSource{{Src: "a.webp"}}},
+		},
+		Img: Img{Src: "a.png", Alt: "a"},
+	}
+	v, err := p.HTML(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, &h.Node{
+		Tag: "picture",
+		Inner: h.Frag{
+			&h.Node{
+				Tag: "source",
+				Attributes: h.Attributes{
+					"srcset": "/W1siYS5hdmlmIiwiYWNiZDE4ZGIiXV0.avif",
+					"type":   "image/avif",
+				},
+				SelfClosing: true,
+			},
+			&h.Node{
+				Tag: "source",
+				Attributes: h.Attributes{
+					"srcset": "/W1siYS53ZWJwIiwiYWNiZDE4ZGIiXV0.webp",
+					"type":   "image/webp",
+				},
+				SelfClosing: true,
+			},
+			&h.Node{
+				Tag: "img",
+				Attributes: h.Attributes{
+					"src": "/W1siYS5wbmciLCJhY2JkMThkYiJdXQ.png",
+					"alt": "a",
+				},
+				SelfClosing: true,
+			},
+		},
+	})
+}
+
+func TestPictureInvalidSource(t *testing.T) {
+	givenErr := errors.New("")
+	ctx := makeCtx(&static.Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			return nil, givenErr
+		}),
+	})
+	p := Picture{
+		Sources: []PictureSource{{Type: "image/webp", SrcSet: []ImgSource{{Src: "a.webp"}}}},
+		Img:     Img{Src: "a.png"},
+	}
+	v, err := p.HTML(ctx)
+	ensure.Nil(t, v)
+	ensure.DeepEqual(t, err, givenErr)
+}
+
+func TestIcons(t *testing.T) {
+	var names []string
+	ctx := makeCtx(&static.Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			names = append(names, name)
+			return []byte("foo"), nil
+		}),
+	})
+	i := Icons{Base: "img/icon", Sizes: []int{16, 32}, AppleTouch: 180}
+	v, err := i.HTML(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, names, []string{
+		"img/icon-16x16.png",
+		"img/icon-32x32.png",
+		"img/icon-180x180.png",
+	})
+	links := v.(h.Frag)
+	ensure.DeepEqual(t, len(links), 3)
+	ensure.DeepEqual(t, links[1].(*h.Node).Attributes, h.Attributes{
+		"rel":   "icon",
+		"type":  "image/png",
+		"sizes": "32x32",
+		"href":  "W1siaW1nL2ljb24tMzJ4MzIucG5nIiwiYWNiZDE4ZGIiXV0.png",
+	})
+	ensure.DeepEqual(t, links[2].(*h.Node).Attributes["rel"], "apple-touch-icon")
+}
+
+func TestIconsInvalid(t *testing.T) {
+	givenErr := errors.New("")
+	ctx := makeCtx(&static.Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			return nil, givenErr
+		}),
+	})
+	i := Icons{Base: "img/icon", AppleTouch: 180}
+	v, err := i.HTML(ctx)
+	ensure.Nil(t, v)
+	ensure.DeepEqual(t, err, givenErr)
+}
+
+func TestWebManifest(t *testing.T) {
+	ctx := makeCtx(&static.Handler{
+		Path: "/",
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("{}"), nil
+		}),
+	})
+	m := WebManifest{HREF: "manifest.webmanifest"}
+	v, err := m.HTML(ctx)
+	ensure.Nil(t, err)
+	url, err := static.URL(ctx, "manifest.webmanifest")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, &h.Node{
+		Tag: "link",
+		Attributes: h.Attributes{
+			"rel":  "manifest",
+			"href": url,
+		},
+		SelfClosing: true,
+	})
+}
+
+func TestVideo(t *testing.T) {
+	ctx := makeCtx(&static.Handler{
+		Path: "/",
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	})
+	v := Video{
+		Poster:   "a.png",
+		Sources:  []MediaSource{{Src: "a.webm", Type: "video/webm"}},
+		Controls: true,
+		Muted:    true,
+		Inner:    h.String("no video"),
+	}
+	out, err := v.HTML(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, out, &h.Node{
+		Tag: "video",
+		Attributes: h.Attributes{
+			"poster":      "/W1siYS5wbmciLCJhY2JkMThkYiJdXQ.png",
+			"controls":    true,
+			"autoplay":    false,
+			"muted":       true,
+			"loop":        false,
+			"playsinline": false,
+		},
+		Inner: h.Frag{
+			&h.Node{
+				Tag: "source",
+				Attributes: h.Attributes{
+					"src":  "/W1siYS53ZWJtIiwiYWNiZDE4ZGIiXV0.webm",
+					"type": "video/webm",
+				},
+				SelfClosing: true,
+			},
+			h.String("no video"),
+		},
+	})
+}
+
+func TestAudio(t *testing.T) {
+	ctx := makeCtx(&static.Handler{
+		Path: "/",
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	})
+	a := Audio{Src: "a.mp3", Controls: true, Preload: "none"}
+	out, err := a.HTML(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, out, &h.Node{
+		Tag: "audio",
+		Attributes: h.Attributes{
+			"src":      "/W1siYS5tcDMiLCJhY2JkMThkYiJdXQ.mp3",
+			"preload":  "none",
+			"controls": true,
+			"autoplay": false,
+			"muted":    false,
+			"loop":     false,
+		},
+		Inner: h.Frag{},
+	})
+}
+
+func TestMediaInvalidSrc(t *testing.T) {
+	givenErr := errors.New("")
+	ctx := makeCtx(&static.Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			return nil, givenErr
+		}),
+	})
+	for _, c := range []h.HTML{
+		&Video{Src: "a.webm"},
+		&Video{Poster: "a.png"},
+		&Audio{Sources: []MediaSource{{Src: "a.mp3"}}},
+	} {
+		v, err := c.HTML(ctx)
+		ensure.Nil(t, v)
+		ensure.DeepEqual(t, err, givenErr)
+	}
+}
+
+func TestInput(t *testing.T) {
+	ctx := makeCtx(&static.Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	})
+	l := Input{Src: "foo"}
+	v, err := l.HTML(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, &h.Input{Src: "W1siZm9vIiwiYWNiZDE4ZGIiXV0"})
+}
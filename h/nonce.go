@@ -0,0 +1,28 @@
+package h
+
+import "context"
+
+type nonceCtxKeyType int
+
+const nonceCtxKey nonceCtxKeyType = 0
+
+// NewNonceContext returns a new context carrying the per-request Content
+// Security Policy nonce, which Script, InlineScript and InlineStyle add to
+// their tags unless they set their own.
+func NewNonceContext(ctx context.Context, nonce string) context.Context {
+	return context.WithValue(ctx, nonceCtxKey, nonce)
+}
+
+// NonceFromContext returns the nonce in the context, or an empty string.
+func NonceFromContext(ctx context.Context) string {
+	nonce, _ := ctx.Value(nonceCtxKey).(string)
+	return nonce
+}
+
+// nonce returns the given nonce if set, or the one in the context.
+func nonce(ctx context.Context, nonce string) string {
+	if nonce != "" {
+		return nonce
+	}
+	return NonceFromContext(ctx)
+}
@@ -0,0 +1,47 @@
+package h
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/daaku/go.h"
+	"github.com/daaku/go.static"
+	"github.com/facebookgo/ensure"
+)
+
+func TestNonceFromContext(t *testing.T) {
+	ensure.DeepEqual(t, NonceFromContext(context.Background()), "")
+	ctx := NewNonceContext(context.Background(), "n")
+	ensure.DeepEqual(t, NonceFromContext(ctx), "n")
+	ensure.DeepEqual(t, nonce(ctx, "o"), "o")
+	ensure.DeepEqual(t, nonce(ctx, ""), "n")
+}
+
+func TestScriptNonce(t *testing.T) {
+	ctx := NewNonceContext(makeCtx(&static.Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	}), "n")
+
+	s := Script{Src: []string{"a.js"}}
+	v, err := s.HTML(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v.(*h.Node).Attributes["nonce"], "n")
+
+	m := ModuleScript{Module: []string{"a.mjs"}}
+	v, err = m.HTML(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v.(*h.Node).Attributes["nonce"], "n")
+
+	i := InlineScript{Src: []string{"a.js"}}
+	v, err = i.HTML(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v.(*h.Node).Attributes["nonce"], "n")
+
+	l := InlineStyle{HREF: []string{"a.css"}, Nonce: "o"}
+	v, err = l.HTML(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v.(*h.Node).Attributes["nonce"], "o")
+}
@@ -0,0 +1,177 @@
+package h
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/daaku/go.h"
+	"github.com/daaku/go.static"
+)
+
+// Preloader is implemented by components referencing files which are worth
+// fetching before the page is parsed, such as LinkStyle and Script.
+type Preloader interface {
+	// Preload returns the URL of the file, and the destination used for the
+	// "as" parameter of a preload link.
+	Preload(ctx context.Context) (url, as string, err error)
+}
+
+// Preload returns the URL of the stylesheet.
+func (l *LinkStyle) Preload(ctx context.Context) (string, string, error) {
+	url, err := static.URL(ctx, l.HREF...)
+	return url, "style", err
+}
+
+// Preload returns the URL of the script.
+func (l *Script) Preload(ctx context.Context) (string, string, error) {
+	url, err := static.URL(ctx, l.Src...)
+	return url, "script", err
+}
+
+// Preload returns the URL of the file.
+func (l *PreloadLink) Preload(ctx context.Context) (string, string, error) {
+	url, err := static.URL(ctx, l.HREF...)
+	return url, l.As, err
+}
+
+// PreloadLink provides a <link rel="preload"> where the HREFs are combined and
+// served using the Resolver in the context, for files needed by the current
+// page which the browser would otherwise discover late.
+type PreloadLink struct {
+	HREF        []string
+	As          string // Destination, such as "font", "image" or "script".
+	Type        string // Optional MIME type, such as "font/woff2".
+	CrossOrigin string // Defaults to "anonymous" for fonts, which require it.
+}
+
+// HTML returns the <link> tag with the appropriate attributes.
+func (l *PreloadLink) HTML(ctx context.Context) (h.HTML, error) {
+	return hintLink(ctx, "preload", l.HREF, "as", l.As, "type", l.Type, "crossorigin", l.crossOrigin())
+}
+
+func (l *PreloadLink) crossOrigin() string {
+	if l.CrossOrigin == "" && l.As == "font" {
+		return "anonymous"
+	}
+	return l.CrossOrigin
+}
+
+// linkCrossOrigin returns the crossorigin parameter of a Link preload header
+// for the component, which fonts require as they are fetched in CORS mode.
+func linkCrossOrigin(c Preloader, as string) string {
+	crossOrigin := ""
+	if l, ok := c.(*PreloadLink); ok {
+		crossOrigin = l.crossOrigin()
+	} else if as == "font" {
+		crossOrigin = "anonymous"
+	}
+	switch crossOrigin {
+	case "":
+		return ""
+	case "anonymous":
+		return "; crossorigin"
+	}
+	return "; crossorigin=" + crossOrigin
+}
+
+// PrefetchLink provides a <link rel="prefetch"> where the HREFs are combined
+// and served using the Resolver in the context, for files likely needed by a
+// later navigation.
+type PrefetchLink struct {
+	HREF []string
+	As   string // Optional destination, such as "script".
+}
+
+// HTML returns the <link> tag with the appropriate attributes.
+func (l *PrefetchLink) HTML(ctx context.Context) (h.HTML, error) {
+	return hintLink(ctx, "prefetch", l.HREF, "as", l.As)
+}
+
+func hintLink(ctx context.Context, rel string, names []string, attrs ...string) (h.HTML, error) {
+	url, err := static.URL(ctx, names...)
+	if err != nil {
+		return nil, err
+	}
+	a := h.Attributes{
+		"rel":  rel,
+		"href": url,
+	}
+	addAttributes(a, attrs...)
+	return &h.Node{
+		Tag:         "link",
+		Attributes:  a,
+		SelfClosing: true,
+	}, nil
+}
+
+// Preconnect provides a <link rel="preconnect"> for an origin serving files,
+// such as a CDN in front of the Handler, so the connection is established
+// before the first file is requested.
+type Preconnect struct {
+	Origin      string // Such as "https://cdn.example.com".
+	CrossOrigin bool   // Required for origins serving fonts and modules.
+}
+
+// HTML returns the <link> tag with the appropriate attributes.
+func (p *Preconnect) HTML(ctx context.Context) (h.HTML, error) {
+	attrs := h.Attributes{
+		"rel":  "preconnect",
+		"href": p.Origin,
+	}
+	if p.CrossOrigin {
+		attrs["crossorigin"] = "anonymous"
+	}
+	return &h.Node{
+		Tag:         "link",
+		Attributes:  attrs,
+		SelfClosing: true,
+	}, nil
+}
+
+// LinkHeader adds a Link preload header for each of the components, so the
+// browser can fetch them while the page is still being generated.
+func LinkHeader(ctx context.Context, header http.Header, components ...Preloader) error {
+	for _, c := range components {
+		url, as, err := c.Preload(ctx)
+		if err != nil {
+			return err
+		}
+		header.Add("Link", fmt.Sprintf("<%s>; rel=preload; as=%s%s", url, as, linkCrossOrigin(c, as)))
+	}
+	return nil
+}
+
+// EarlyHints adds the Link preload headers for the components, and sends them
+// immediately in a 103 Early Hints response. The headers remain set for the
+// final response.
+func EarlyHints(ctx context.Context, w http.ResponseWriter, components ...Preloader) error {
+	if err := LinkHeader(ctx, w.Header(), components...); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusEarlyHints)
+	return nil
+}
+
+// Push pushes the files used by the components with HTTP/2 server push, when
+// the client supports it. It does nothing for other clients.
+func Push(ctx context.Context, w http.ResponseWriter, components ...Preloader) error {
+	pusher, ok := w.(http.Pusher)
+	if !ok {
+		return nil
+	}
+	for _, c := range components {
+		url, _, err := c.Preload(ctx)
+		if err != nil {
+			return err
+		}
+		if err := pusher.Push(url, nil); err != nil {
+			if errors.Is(err, http.ErrNotSupported) {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package h
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/daaku/go.h"
+	"github.com/daaku/go.static"
+	"github.com/facebookgo/ensure"
+)
+
+func TestPreloadLink(t *testing.T) {
+	ctx := makeCtx(&static.Handler{
+		Path: "/static/",
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	})
+	l := PreloadLink{HREF: []string{"foo.woff2"}, As: "font", Type: "font/woff2"}
+	v, err := l.HTML(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, &h.Node{
+		Tag: "link",
+		Attributes: h.Attributes{
+			"rel":         "preload",
+			"href":        "/static/W1siZm9vLndvZmYyIiwiYWNiZDE4ZGIiXV0.woff2",
+			"as":          "font",
+			"type":        "font/woff2",
+			"crossorigin": "anonymous",
+		},
+		SelfClosing: true,
+	})
+
+	header := http.Header{}
+	ensure.Nil(t, LinkHeader(ctx, header, &l))
+	ensure.DeepEqual(t, header.Get("Link"),
+		"</static/W1siZm9vLndvZmYyIiwiYWNiZDE4ZGIiXV0.woff2>; rel=preload; as=font; crossorigin")
+
+	l.CrossOrigin = "use-credentials"
+	header = http.Header{}
+	ensure.Nil(t, LinkHeader(ctx, header, &l))
+	ensure.DeepEqual(t, header.Get("Link"),
+		"</static/W1siZm9vLndvZmYyIiwiYWNiZDE4ZGIiXV0.woff2>; rel=preload; as=font; crossorigin=use-credentials")
+}
+
+func TestPrefetchLink(t *testing.T) {
+	ctx := makeCtx(&static.Handler{
+		Path: "/static/",
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	})
+	l := PrefetchLink{HREF: []string{"foo.js"}}
+	v, err := l.HTML(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, &h.Node{
+		Tag: "link",
+		Attributes: h.Attributes{
+			"rel":  "prefetch",
+			"href": "/static/W1siZm9vLmpzIiwiYWNiZDE4ZGIiXV0.js",
+		},
+		SelfClosing: true,
+	})
+}
+
+func TestPreconnect(t *testing.T) {
+	p := Preconnect{Origin: "https://cdn.example.com", CrossOrigin: true}
+	v, err := p.HTML(makeCtx(&static.Handler{}))
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, &h.Node{
+		Tag: "link",
+		Attributes: h.Attributes{
+			"rel":         "preconnect",
+			"href":        "https://cdn.example.com",
+			"crossorigin": "anonymous",
+		},
+		SelfClosing: true,
+	})
+}
+
+func TestLinkHeader(t *testing.T) {
+	ctx := makeCtx(&static.Handler{
+		Path: "/static/",
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	})
+	header := http.Header{}
+	err := LinkHeader(ctx, header,
+		&LinkStyle{HREF: []string{"foo.css"}},
+		&Script{Src: []string{"foo.js"}},
+	)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, header["Link"], []string{
+		"</static/W1siZm9vLmNzcyIsImFjYmQxOGRiIl1d.css>; rel=preload; as=style",
+		"</static/W1siZm9vLmpzIiwiYWNiZDE4ZGIiXV0.js>; rel=preload; as=script",
+	})
+}
+
+func TestLinkHeaderError(t *testing.T) {
+	givenErr := errors.New("")
+	ctx := makeCtx(&static.Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			return nil, givenErr
+		}),
+	})
+	err := LinkHeader(ctx, http.Header{}, &Script{Src: []string{"foo.js"}})
+	ensure.DeepEqual(t, err, givenErr)
+}
+
+func TestEarlyHints(t *testing.T) {
+	ctx := makeCtx(&static.Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	})
+	w := httptest.NewRecorder()
+	ensure.Nil(t, EarlyHints(ctx, w, &Script{Src: []string{"foo.js"}}))
+	ensure.DeepEqual(t, len(w.Header()["Link"]), 1)
+}
+
+type recordPusher struct {
+	http.ResponseWriter
+	pushed []string
+	err    error
+}
+
+func (p *recordPusher) Push(target string, opts *http.PushOptions) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.pushed = append(p.pushed, target)
+	return nil
+}
+
+func TestPush(t *testing.T) {
+	ctx := makeCtx(&static.Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	})
+	p := &recordPusher{ResponseWriter: httptest.NewRecorder()}
+	ensure.Nil(t, Push(ctx, p, &LinkStyle{HREF: []string{"foo.css"}}))
+	ensure.DeepEqual(t, p.pushed, []string{"W1siZm9vLmNzcyIsImFjYmQxOGRiIl1d.css"})
+
+	p = &recordPusher{ResponseWriter: httptest.NewRecorder(), err: http.ErrNotSupported}
+	ensure.Nil(t, Push(ctx, p, &LinkStyle{HREF: []string{"foo.css"}}))
+
+	givenErr := errors.New("")
+	p = &recordPusher{ResponseWriter: httptest.NewRecorder(), err: givenErr}
+	ensure.DeepEqual(t, Push(ctx, p, &LinkStyle{HREF: []string{"foo.css"}}), givenErr)
+
+	ensure.Nil(t, Push(ctx, httptest.NewRecorder(), &LinkStyle{HREF: []string{"foo.css"}}))
+}
@@ -0,0 +1,111 @@
+package h
+
+import (
+	"context"
+	"reflect"
+	"strings"
+
+	"github.com/daaku/go.h"
+	"github.com/daaku/go.static"
+)
+
+// rewriteAttributes are the attributes containing URLs which Rewrite hashes.
+var rewriteAttributes = []string{"src", "href", "poster", "srcset"}
+
+const goHPkgPath = "github.com/daaku/go.h"
+
+// Rewrite returns a copy of the tree where src, href, poster and srcset
+// attributes referencing files under root, such as "/static/", are replaced
+// with their hashed URLs using the Resolver in the context, keeping any query
+// or fragment. Those which cannot be resolved are left unchanged. This adds
+// fingerprinting to existing templates without switching each node to the
+// components in this package. The go.h components in the tree are rendered to
+// find their attributes, while others are left as is.
+func Rewrite(ctx context.Context, html h.HTML, root string) (h.HTML, error) {
+	switch v := html.(type) {
+	case nil, h.String, h.Unsafe:
+		return html, nil
+	case h.Frag:
+		frag := make(h.Frag, 0, len(v))
+		for _, c := range v {
+			r, err := Rewrite(ctx, c, root)
+			if err != nil {
+				return nil, err
+			}
+			frag = append(frag, r)
+		}
+		return frag, nil
+	case *h.Node:
+		n := *v
+		if v.Attributes != nil {
+			n.Attributes = make(h.Attributes, len(v.Attributes))
+			for k, a := range v.Attributes {
+				n.Attributes[k] = a
+			}
+		}
+		for _, k := range rewriteAttributes {
+			value, ok := n.Attributes[k].(string)
+			if !ok {
+				continue
+			}
+			if k == "srcset" {
+				n.Attributes[k] = rewriteSrcSet(ctx, value, root)
+			} else {
+				n.Attributes[k] = rewriteURL(ctx, value, root)
+			}
+		}
+		inner, err := Rewrite(ctx, v.Inner, root)
+		if err != nil {
+			return nil, err
+		}
+		n.Inner = inner
+		return &n, nil
+	}
+	t := reflect.TypeOf(html)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.PkgPath() != goHPkgPath {
+		return html, nil
+	}
+	rendered, err := html.HTML(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return Rewrite(ctx, rendered, root)
+}
+
+// rewriteURL returns the hashed URL for value if it is under root, keeping
+// any query or fragment. Values which cannot be resolved, such as links to
+// files which do not exist, are left as is.
+func rewriteURL(ctx context.Context, value, root string) string {
+	if !strings.HasPrefix(value, root) {
+		return value
+	}
+	name, suffix := value[len(root):], ""
+	if i := strings.IndexAny(name, "?#"); i >= 0 {
+		name, suffix = name[:i], name[i:]
+	}
+	if name == "" {
+		return value
+	}
+	url, err := static.URL(ctx, name)
+	if err != nil {
+		return value
+	}
+	return url + suffix
+}
+
+// rewriteSrcSet rewrites the URLs of each candidate in a srcset.
+func rewriteSrcSet(ctx context.Context, value, root string) string {
+	candidates := strings.Split(value, ",")
+	for i, c := range candidates {
+		fields := strings.Fields(c)
+		if len(fields) == 0 {
+			continue
+		}
+		fields[0] = rewriteURL(ctx, fields[0], root)
+		candidates[i] = strings.Join(fields, " ")
+	}
+	return strings.Join(candidates, ", ")
+}
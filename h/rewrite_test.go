@@ -0,0 +1,106 @@
+package h
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/daaku/go.h"
+	"github.com/daaku/go.static"
+	"github.com/facebookgo/ensure"
+)
+
+func TestRewrite(t *testing.T) {
+	ctx := makeCtx(&static.Handler{
+		Path: "/static/",
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	})
+	link := &h.Node{
+		Tag:        "a",
+		Attributes: h.Attributes{"href": "/about"},
+		Inner:      h.String("about"),
+	}
+	tree := h.Frag{
+		&h.Script{Src: "/static/a.js"},
+		&h.Node{
+			Tag: "div",
+			Inner: &h.Node{
+				Tag: "img",
+				Attributes: h.Attributes{
+					"src":    "/static/a.png",
+					"srcset": "/static/a.png 1x, /static/b.png 2x",
+				},
+				SelfClosing: true,
+			},
+		},
+		link,
+	}
+	v, err := Rewrite(ctx, tree, "/static/")
+	ensure.Nil(t, err)
+	frag := v.(h.Frag)
+	ensure.DeepEqual(t, frag[0].(*h.Node).Attributes["src"],
+		"/static/W1siYS5qcyIsImFjYmQxOGRiIl1d.js")
+	img := frag[1].(*h.Node).Inner.(*h.Node)
+	ensure.DeepEqual(t, img.Attributes["src"], "/static/W1siYS5wbmciLCJhY2JkMThkYiJdXQ.png")
+	ensure.DeepEqual(t, img.Attributes["srcset"],
+		"/static/W1siYS5wbmciLCJhY2JkMThkYiJdXQ.png 1x, "+
+			"/static/W1siYi5wbmciLCJhY2JkMThkYiJdXQ.png 2x")
+	ensure.DeepEqual(t, frag[2], link)
+
+	// the original tree is not modified
+	ensure.DeepEqual(t, tree[1].(*h.Node).Inner.(*h.Node).Attributes["src"], "/static/a.png")
+}
+
+func TestRewriteSkipsComponents(t *testing.T) {
+	ctx := makeCtx(&static.Handler{
+		Path: "/static/",
+		Box: funcBox(func(name string) ([]byte, error) {
+			panic("not reached")
+		}),
+	})
+	s := &Script{Src: []string{"a.js"}}
+	v, err := Rewrite(ctx, s, "/static/")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, s)
+}
+
+func TestRewriteSuffix(t *testing.T) {
+	ctx := makeCtx(&static.Handler{
+		Path: "/static/",
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	})
+	v, err := Rewrite(ctx, h.Frag{
+		&h.Node{Tag: "img", Attributes: h.Attributes{"src": "/static/a.svg#icon"}},
+		&h.Node{Tag: "a", Attributes: h.Attributes{"href": "/static/a.css?v=1#top"}},
+	}, "/static/")
+	ensure.Nil(t, err)
+	frag := v.(h.Frag)
+	ensure.DeepEqual(t, frag[0].(*h.Node).Attributes["src"],
+		"/static/W1siYS5zdmciLCJhY2JkMThkYiJdXQ.svg#icon")
+	ensure.DeepEqual(t, frag[1].(*h.Node).Attributes["href"],
+		"/static/W1siYS5jc3MiLCJhY2JkMThkYiJdXQ.css?v=1#top")
+}
+
+func TestRewriteUnresolved(t *testing.T) {
+	ctx := makeCtx(&static.Handler{
+		Path: "/static/",
+		Box: funcBox(func(name string) ([]byte, error) {
+			if name == "a.png" {
+				return []byte("foo"), nil
+			}
+			return nil, errors.New("")
+		}),
+	})
+	v, err := Rewrite(ctx, h.Frag{
+		&h.Node{Tag: "video", Attributes: h.Attributes{"poster": "/static/missing.png"}},
+		&h.Node{Tag: "img", Attributes: h.Attributes{"srcset": "/static/a.png 1x, /static/missing.png 2x"}},
+	}, "/static/")
+	ensure.Nil(t, err)
+	frag := v.(h.Frag)
+	ensure.DeepEqual(t, frag[0].(*h.Node).Attributes["poster"], "/static/missing.png")
+	ensure.DeepEqual(t, frag[1].(*h.Node).Attributes["srcset"],
+		"/static/W1siYS5wbmciLCJhY2JkMThkYiJdXQ.png 1x, /static/missing.png 2x")
+}
@@ -0,0 +1,41 @@
+package static
+
+import (
+	"net/http"
+	"strings"
+)
+
+var allowAnyOrigin = http.Header{"Access-Control-Allow-Origin": {"*"}}
+
+// defaultExtHeaders are used when ExtHeaders is nil.
+var defaultExtHeaders = map[string]http.Header{
+	".eot":   allowAnyOrigin,
+	".otf":   allowAnyOrigin,
+	".ttf":   allowAnyOrigin,
+	".woff":  allowAnyOrigin,
+	".woff2": allowAnyOrigin,
+	".wasm":  allowAnyOrigin,
+}
+
+// securityHeaders adds the headers commonly expected by security scanners.
+func (h *Handler) securityHeaders(w http.ResponseWriter) {
+	header := w.Header()
+	header.Set("X-Content-Type-Options", "nosniff")
+	if h.CrossOriginResourcePolicy != "" {
+		header.Set("Cross-Origin-Resource-Policy", h.CrossOriginResourcePolicy)
+	}
+}
+
+// extHeaders adds the ExtHeaders for the extension.
+func (h *Handler) extHeaders(w http.ResponseWriter, ext string) {
+	rules := h.ExtHeaders
+	if rules == nil {
+		rules = defaultExtHeaders
+	}
+	header := w.Header()
+	for key, values := range rules[strings.ToLower(ext)] {
+		for _, v := range values {
+			header.Add(key, v)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package static
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/ensure"
+)
+
+// serveURL serves the URL for the names, with the given request headers.
+func serveURL(t *testing.T, h *Handler, header http.Header, names ...string) *httptest.ResponseRecorder {
+	v, err := h.URL(names...)
+	ensure.Nil(t, err)
+	r := httptest.NewRequest("GET", v, nil)
+	for k, values := range header {
+		r.Header[k] = values
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestExtHeadersDefault(t *testing.T) {
+	h := &Handler{
+		Path: "/",
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	}
+	w := serveURL(t, h, nil, "font.woff2")
+	ensure.DeepEqual(t, w.Header().Get("Access-Control-Allow-Origin"), "*")
+	w = serveURL(t, h, nil, "app.wasm")
+	ensure.DeepEqual(t, w.Header().Get("Access-Control-Allow-Origin"), "*")
+	w = serveURL(t, h, nil, "app.css")
+	ensure.DeepEqual(t, w.Header().Get("Access-Control-Allow-Origin"), "")
+}
+
+func TestExtHeadersCustom(t *testing.T) {
+	h := &Handler{
+		Path: "/",
+		ExtHeaders: map[string]http.Header{
+			".css": {"Access-Control-Allow-Origin": {"https://example.com"}},
+		},
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	}
+	w := serveURL(t, h, nil, "app.css")
+	ensure.DeepEqual(t, w.Header().Get("Access-Control-Allow-Origin"), "https://example.com")
+	w = serveURL(t, h, nil, "font.woff2")
+	ensure.DeepEqual(t, w.Header().Get("Access-Control-Allow-Origin"), "")
+}
+
+func TestHeaderFunc(t *testing.T) {
+	var names []string
+	h := New(
+		WithPath("/"),
+		WithBox(funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		})),
+		WithHeaderFunc(func(w http.ResponseWriter, name string) {
+			names = append(names, name)
+			w.Header().Set("Timing-Allow-Origin", "*")
+		}),
+	)
+	w := serveURL(t, h, nil, "a.css", "b.css")
+	ensure.DeepEqual(t, w.Header().Get("Timing-Allow-Origin"), "*")
+	ensure.DeepEqual(t, names, []string{"a.css"})
+}
+
+func TestImmutable(t *testing.T) {
+	h := &Handler{
+		Path:      "/",
+		MaxAge:    time.Hour,
+		Immutable: true,
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	}
+	w := serveURL(t, h, nil, "a.css")
+	ensure.DeepEqual(t, w.Header().Get("Cache-Control"), "public, max-age=3600, immutable")
+	h.MaxAge = 0
+	ensure.DeepEqual(t, h.cacheControl(), defaultCacheControl+", immutable")
+}
+
+func TestStaleDirectives(t *testing.T) {
+	h := &Handler{
+		MaxAge:               time.Hour,
+		StaleWhileRevalidate: time.Minute,
+		StaleIfError:         24 * time.Hour,
+	}
+	ensure.DeepEqual(t, h.cacheControl(),
+		"public, max-age=3600, stale-while-revalidate=60, stale-if-error=86400")
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	h := &Handler{
+		Path: "/",
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	}
+	w := serveURL(t, h, nil, "a.css")
+	ensure.DeepEqual(t, w.Header().Get("X-Content-Type-Options"), "nosniff")
+	ensure.DeepEqual(t, w.Header().Get("Cross-Origin-Resource-Policy"), "")
+
+	h.CrossOriginResourcePolicy = "cross-origin"
+	h.HeaderFunc = func(w http.ResponseWriter, name string) {
+		w.Header().Del("X-Content-Type-Options")
+	}
+	w = serveURL(t, h, nil, "a.css")
+	ensure.DeepEqual(t, w.Header().Get("X-Content-Type-Options"), "")
+	ensure.DeepEqual(t, w.Header().Get("Cross-Origin-Resource-Policy"), "cross-origin")
+}
@@ -0,0 +1,67 @@
+package static
+
+import (
+	"bytes"
+	"context"
+	"image"
+	_ "image/gif"  // register the GIF decoder
+	_ "image/jpeg" // register the JPEG decoder
+	_ "image/png"  // register the PNG decoder
+)
+
+// ImageSizeResolver is a Resolver which also provides the dimensions of
+// images, such as for the width and height attributes of an <img>.
+type ImageSizeResolver interface {
+	Resolver
+	ImageSizeContext(ctx context.Context, name string) (width, height int, err error)
+}
+
+var _ ImageSizeResolver = (*Handler)(nil)
+
+// imageSize is the remembered dimensions of a version of an image.
+type imageSize struct {
+	hash          string
+	width, height int
+}
+
+// ImageSizeContext returns the dimensions of the named GIF, JPEG or PNG image,
+// or zeros for other types of files and images which cannot be decoded. The
+// dimensions are remembered until the file changes.
+func (h *Handler) ImageSizeContext(ctx context.Context, name string) (int, int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, 0, err
+	}
+	if err := validName(name); err != nil {
+		return 0, 0, err
+	}
+	name = cleanName(name)
+	f, err := h.load(name)
+	if err != nil {
+		return 0, 0, err
+	}
+	if v, found := h.sizes.Load(name); found && v.(imageSize).hash == f.Hash {
+		return v.(imageSize).width, v.(imageSize).height, nil
+	}
+	contents := f.Content
+	if contents == nil {
+		if contents, err = h.bytes(name); err != nil {
+			return 0, 0, err
+		}
+	}
+	size := imageSize{hash: f.Hash}
+	if config, _, err := image.DecodeConfig(bytes.NewReader(contents)); err == nil {
+		size.width, size.height = config.Width, config.Height
+	}
+	h.sizes.Store(name, size)
+	return size.width, size.height, nil
+}
+
+// ImageSize returns the dimensions of the named image using the Resolver in
+// the context. It returns zeros if the Resolver does not provide them.
+func ImageSize(ctx context.Context, name string) (int, int, error) {
+	r, ok := FromContext(ctx).(ImageSizeResolver)
+	if !ok {
+		return 0, 0, nil
+	}
+	return r.ImageSizeContext(ctx, name)
+}
@@ -0,0 +1,88 @@
+package static
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+	"testing/fstest"
+
+	"golang.org/x/net/context"
+
+	"github.com/facebookgo/ensure"
+)
+
+func pngBytes(t *testing.T, width, height int) []byte {
+	var buf bytes.Buffer
+	ensure.Nil(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height))))
+	return buf.Bytes()
+}
+
+func TestImageSize(t *testing.T) {
+	img := pngBytes(t, 3, 2)
+	h := &Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			if name == "a.png" {
+				return img, nil
+			}
+			return []byte("body{}"), nil
+		}),
+	}
+	width, height, err := ImageSize(makeCtx(h), "a.png")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, width, 3)
+	ensure.DeepEqual(t, height, 2)
+
+	width, height, err = ImageSize(makeCtx(h), "a.css")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, width, 0)
+	ensure.DeepEqual(t, height, 0)
+}
+
+func TestImageSizeTruncated(t *testing.T) {
+	h := &Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			return pngBytes(t, 3, 2)[:20], nil
+		}),
+	}
+	width, height, err := ImageSize(makeCtx(h), "a.png")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, width, 0)
+	ensure.DeepEqual(t, height, 0)
+}
+
+// countingBox counts the reads of file contents.
+type countingBox struct {
+	StatBox
+	loads int
+}
+
+func (b *countingBox) Bytes(name string) ([]byte, error) {
+	b.loads++
+	return b.StatBox.Bytes(name)
+}
+
+func TestImageSizeCached(t *testing.T) {
+	box := &countingBox{StatBox: FSBox(fstest.MapFS{
+		"a.png": {Data: pngBytes(t, 3, 2)},
+	}).(StatBox)}
+	h := &Handler{HashMetadata: true, Box: box}
+	for i := 0; i < 2; i++ {
+		width, height, err := ImageSize(makeCtx(h), "a.png")
+		ensure.Nil(t, err)
+		ensure.DeepEqual(t, [2]int{width, height}, [2]int{3, 2})
+	}
+	ensure.DeepEqual(t, box.loads, 1)
+
+	h.ClearCache()
+	_, _, err := ImageSize(makeCtx(h), "a.png")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, box.loads, 2)
+}
+
+func TestImageSizeUnsupportedResolver(t *testing.T) {
+	width, height, err := ImageSize(NewContext(context.Background(), urlResolver{}), "a.png")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, width, 0)
+	ensure.DeepEqual(t, height, 0)
+}
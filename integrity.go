@@ -0,0 +1,77 @@
+package static
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/base64"
+)
+
+// IntegrityResolver is a Resolver which also provides Subresource Integrity
+// values for the URLs it generates.
+type IntegrityResolver interface {
+	Resolver
+	IntegrityContext(ctx context.Context, names ...string) (string, error)
+}
+
+var _ IntegrityResolver = (*Handler)(nil)
+
+// maxDigestBytes bounds the memory used to remember integrity values.
+const maxDigestBytes = 1 << 20
+
+// IntegrityContext returns the sha384 Subresource Integrity value for the
+// combined contents of the given names, or an empty string if SRI is not
+// enabled. Values are remembered by the names and hashes of the files, and
+// streamed files are hashed without being read into memory.
+func (h *Handler) IntegrityContext(ctx context.Context, names ...string) (string, error) {
+	if !h.SRI {
+		return "", nil
+	}
+	if len(names) == 0 {
+		return "", errZeroNames
+	}
+	names, err := h.bundleNames(names)
+	if err != nil {
+		return "", err
+	}
+	files := make([]file, 0, len(names))
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		f, err := h.load(name)
+		if err != nil {
+			return "", err
+		}
+		files = append(files, f)
+	}
+	key := etag(files)
+	if d, found := h.digests.get(key, h.CacheTTL); found {
+		return string(d.Content), nil
+	}
+
+	for i, f := range files {
+		if f.Content == nil && f.Streamed == 0 {
+			if files[i].Content, err = h.bytes(f.Name); err != nil {
+				return "", err
+			}
+		}
+	}
+	hs := sha512.New384()
+	if err := h.writeFiles(hs, files); err != nil {
+		return "", err
+	}
+	integrity := "sha384-" + base64.StdEncoding.EncodeToString(hs.Sum(nil))
+	h.digests.add(file{Name: key, Content: []byte(integrity)}, maxDigestBytes, h.CacheTTL)
+	return integrity, nil
+}
+
+// Integrity returns the Subresource Integrity value for the given names using
+// the Resolver in the context. It returns an empty string if the Resolver does
+// not provide them.
+func Integrity(ctx context.Context, names ...string) (string, error) {
+	r, ok := FromContext(ctx).(IntegrityResolver)
+	if !ok {
+		return "", nil
+	}
+	return r.IntegrityContext(ctx, names...)
+}
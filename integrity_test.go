@@ -0,0 +1,89 @@
+package static
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+	"testing"
+	"testing/fstest"
+
+	"golang.org/x/net/context"
+
+	"github.com/facebookgo/ensure"
+)
+
+type urlResolver struct{}
+
+func (urlResolver) URLContext(ctx context.Context, names ...string) (string, error) {
+	return "url", nil
+}
+
+func TestIntegrityDisabled(t *testing.T) {
+	h := &Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			panic("not reached")
+		}),
+	}
+	v, err := Integrity(makeCtx(h), "foo")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, "")
+}
+
+func TestIntegrityUnsupportedResolver(t *testing.T) {
+	v, err := Integrity(NewContext(context.Background(), urlResolver{}), "foo")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, "")
+}
+
+func TestIntegrityCombined(t *testing.T) {
+	contents := map[string]string{"a": "foo", "b": "bar"}
+	h := &Handler{
+		SRI: true,
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte(contents[name]), nil
+		}),
+	}
+	v, err := Integrity(makeCtx(h), "a", "b")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, "sha384-PJww2fZl501RXIQpYNSkUcg6ASX9Pec5LXs3IxrxDHLqWK7fzfiaV2W/kCr5Ps8G")
+}
+
+func TestIntegrityRemembered(t *testing.T) {
+	box := &countingBox{StatBox: FSBox(fstest.MapFS{
+		"a.js": {Data: []byte("foobar")},
+	}).(StatBox)}
+	h := &Handler{SRI: true, HashMetadata: true, Box: box}
+	for i := 0; i < 2; i++ {
+		v, err := Integrity(makeCtx(h), "a.js")
+		ensure.Nil(t, err)
+		ensure.DeepEqual(t, v, integrityOf("foobar"))
+	}
+	ensure.DeepEqual(t, box.loads, 1)
+	ensure.DeepEqual(t, h.digests.len(), 1)
+}
+
+func TestIntegrityStreamed(t *testing.T) {
+	h := &Handler{
+		SRI:        true,
+		StreamSize: 1,
+		Box: FSBox(fstest.MapFS{
+			"a.js": {Data: []byte("foobar")},
+		}),
+	}
+	v, err := Integrity(makeCtx(h), "a.js")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, integrityOf("foobar"))
+	cached, found := h.cache().get("a.js", 0)
+	ensure.True(t, found)
+	ensure.True(t, cached.Content == nil)
+}
+
+func integrityOf(contents string) string {
+	sum := sha512.Sum384([]byte(contents))
+	return "sha384-" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func TestIntegrityZeroNames(t *testing.T) {
+	h := &Handler{SRI: true}
+	_, err := h.IntegrityContext(context.Background())
+	ensure.True(t, err == errZeroNames, err)
+}
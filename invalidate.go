@@ -0,0 +1,70 @@
+package static
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// ClearCache drops all cached files and failures, so files are loaded from
+// the Box again. Files from LoadCache, a shared Cache or CacheDir are kept, as
+// they are only used for URLs with matching hashes.
+func (h *Handler) ClearCache() {
+	h.cache().clear()
+	h.negative.clear()
+	h.variants.clear()
+	h.digests.clear()
+	h.sizes.Range(func(k, _ interface{}) bool {
+		h.sizes.Delete(k)
+		return true
+	})
+}
+
+// InvalidateURL drops everything cached for the files in a URL previously
+// returned by the Handler, including copies in LoadCache data, the shared
+// Cache and CacheDir, so they are loaded from the Box again.
+func (h *Handler) InvalidateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	prefix := h.prefix()
+	if !strings.HasPrefix(u.Path, prefix) {
+		return errInvalidURL(rawURL)
+	}
+	files, _, err := h.parseRequest(&http.Request{URL: u}, u.Path[len(prefix):])
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	for _, f := range files {
+		delete(h.saved, savedKey(f.Name, f.Hash))
+	}
+	h.mu.Unlock()
+
+	if key, err := encode(files); err == nil {
+		h.negative.remove(key)
+	}
+	for _, f := range files {
+		h.cache().remove(f.Name)
+		if h.Cache != nil {
+			if err := h.Cache.Delete(cacheKey(f.Name, f.Hash)); err != nil {
+				return err
+			}
+		}
+	}
+	if h.CacheDir != "" {
+		p, err := h.diskPath(files)
+		if err != nil {
+			return err
+		}
+		if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package static
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestClearCache(t *testing.T) {
+	var loads int
+	h := &Handler{
+		Path:             "/",
+		NegativeCacheTTL: time.Minute,
+		Box: funcBox(func(name string) ([]byte, error) {
+			loads++
+			return []byte("foo"), nil
+		}),
+	}
+	_, err := h.URL("a.css")
+	ensure.Nil(t, err)
+	h.negative.add("key", http.StatusNotFound, time.Minute)
+	h.ClearCache()
+	ensure.DeepEqual(t, h.cache().len(), 0)
+	_, found := h.negative.get("key")
+	ensure.False(t, found)
+	_, err = h.URL("a.css")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, loads, 2)
+}
+
+func TestInvalidateURL(t *testing.T) {
+	shared := mapCache{}
+	h := &Handler{
+		Path:     "/static/",
+		Cache:    shared,
+		CacheDir: t.TempDir(),
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte(name), nil
+		}),
+	}
+	v, err := h.URL("a.css", "b.css")
+	ensure.Nil(t, err)
+	_, err = h.URL("c.css")
+	ensure.Nil(t, err)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", v, nil))
+	ensure.DeepEqual(t, w.Code, http.StatusOK)
+
+	files, _, err := h.parseRequest(httptest.NewRequest("GET", v, nil), v[len("/static/"):])
+	ensure.Nil(t, err)
+	p, err := h.diskPath(files)
+	ensure.Nil(t, err)
+	_, err = os.Stat(p)
+	ensure.Nil(t, err)
+
+	ensure.Nil(t, h.InvalidateURL(v))
+	ensure.DeepEqual(t, h.cache().len(), 1)
+	ensure.DeepEqual(t, len(shared), 1)
+	_, err = os.Stat(p)
+	ensure.True(t, os.IsNotExist(err))
+}
+
+func TestInvalidateURLQueryVersion(t *testing.T) {
+	h := &Handler{
+		Path:         "/",
+		QueryVersion: true,
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	}
+	v, err := h.URL("a.css")
+	ensure.Nil(t, err)
+	ensure.Nil(t, h.InvalidateURL(v))
+	ensure.DeepEqual(t, h.cache().len(), 0)
+}
+
+func TestInvalidateURLInvalid(t *testing.T) {
+	h := &Handler{Path: "/static/"}
+	ensure.Err(t, h.InvalidateURL("/other/foo"), regexp.MustCompile(`static: invalid URL "/other/foo"`))
+	ensure.NotNil(t, h.InvalidateURL("/static/!!"))
+}
@@ -0,0 +1,139 @@
+package static
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ManifestEntry describes a URL generated by a Handler.
+type ManifestEntry struct {
+	Names   []string  `json:"names"`
+	URL     string    `json:"url"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mtime"`
+}
+
+func manifestKey(names []string) string {
+	return strings.Join(names, "\x00")
+}
+
+// remember records the URL generated for the files for the manifest.
+func (h *Handler) remember(files []file, u string) {
+	names := make([]string, 0, len(files))
+	for _, f := range files {
+		names = append(names, f.Name)
+	}
+	key := manifestKey(names)
+
+	h.mu.RLock()
+	e, found := h.urls[key]
+	h.mu.RUnlock()
+	if found && e.URL == u {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.urls == nil {
+		h.urls = make(map[string]ManifestEntry)
+	}
+	h.urls[key] = ManifestEntry{Names: names, URL: u}
+}
+
+// describe fills in the size and latest modification time of the entry. The
+// size of transformed files is that of their transformed contents.
+func (h *Handler) describe(e *ManifestEntry) error {
+	sb, _ := h.Box.(StatBox)
+	for _, name := range e.Names {
+		if sb != nil {
+			fi, err := sb.Stat(name)
+			if err == nil {
+				if fi.ModTime().After(e.ModTime) {
+					e.ModTime = fi.ModTime()
+				}
+				if !h.transformed(name) {
+					e.Size += fi.Size()
+					continue
+				}
+			} else if !errors.Is(err, errNoStat) {
+				return err
+			}
+		}
+		f, err := h.load(name)
+		if err != nil {
+			return err
+		}
+		if f.Content == nil {
+			if f.Content, err = h.bytes(name); err != nil {
+				return err
+			}
+		}
+		e.Size += int64(len(f.Content))
+	}
+	return nil
+}
+
+// Manifest returns the URLs generated so far, sorted by URL.
+func (h *Handler) Manifest() ([]ManifestEntry, error) {
+	h.mu.RLock()
+	entries := make([]ManifestEntry, 0, len(h.urls))
+	for _, e := range h.urls {
+		entries = append(entries, e)
+	}
+	h.mu.RUnlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].URL < entries[j].URL
+	})
+	for i := range entries {
+		if err := h.describe(&entries[i]); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// LoadManifest loads a manifest written by WriteManifest, possibly by another
+// process or a build step. URLs for the names in the manifest are then
+// returned without reading the files.
+func (h *Handler) LoadManifest(r io.Reader) error {
+	var entries []ManifestEntry
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.manifest == nil {
+		h.manifest = make(map[string]string, len(entries))
+	}
+	for _, e := range entries {
+		h.manifest[manifestKey(e.Names)] = e.URL
+	}
+	return nil
+}
+
+// lookupManifest returns the loaded URL for the names, which must already be
+// cleaned by bundleNames.
+func (h *Handler) lookupManifest(names []string) (string, bool) {
+	h.mu.RLock()
+	u, found := h.manifest[manifestKey(names)]
+	h.mu.RUnlock()
+	return u, found
+}
+
+// WriteManifest writes the URLs generated so far as JSON, for use by service
+// workers, other processes or audits.
+func (h *Handler) WriteManifest(w io.Writer) error {
+	entries, err := h.Manifest()
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(entries)
+}
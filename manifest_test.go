@@ -0,0 +1,131 @@
+package static
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestManifest(t *testing.T) {
+	h := &Handler{
+		Path: "/static/",
+		Box: FSBox(fstest.MapFS{
+			"a.css": &fstest.MapFile{Data: []byte("foo"), ModTime: time.Unix(1, 0)},
+			"b.css": &fstest.MapFile{Data: []byte("barbaz"), ModTime: time.Unix(2, 0)},
+		}),
+	}
+	u1, err := h.URL("a.css")
+	ensure.Nil(t, err)
+	u2, err := h.URL("a.css", "b.css")
+	ensure.Nil(t, err)
+	_, err = h.URL("a.css")
+	ensure.Nil(t, err)
+
+	entries, err := h.Manifest()
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, entries, []ManifestEntry{
+		{Names: []string{"a.css", "b.css"}, URL: u2, Size: 9, ModTime: time.Unix(2, 0)},
+		{Names: []string{"a.css"}, URL: u1, Size: 3, ModTime: time.Unix(1, 0)},
+	})
+
+	var buf bytes.Buffer
+	ensure.Nil(t, h.WriteManifest(&buf))
+	ensure.StringContains(t, buf.String(), `"url": "`+u1+`"`)
+	ensure.StringContains(t, buf.String(), `"size": 9`)
+}
+
+func TestManifestWithoutStat(t *testing.T) {
+	h := &Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	}
+	u, err := h.URL("a")
+	ensure.Nil(t, err)
+	entries, err := h.Manifest()
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, entries, []ManifestEntry{
+		{Names: []string{"a"}, URL: u, Size: 3},
+	})
+}
+
+func TestManifestTransformed(t *testing.T) {
+	h := &Handler{
+		Box: FSBox(fstest.MapFS{
+			"a.css": &fstest.MapFile{Data: []byte("foo"), ModTime: time.Unix(1, 0)},
+		}),
+	}
+	WithTransform(".css", func(name string, in []byte) ([]byte, error) {
+		return append(in, "bar"...), nil
+	})(h)
+	u, err := h.URL("a.css")
+	ensure.Nil(t, err)
+	entries, err := h.Manifest()
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, entries, []ManifestEntry{
+		{Names: []string{"a.css"}, URL: u, Size: 6, ModTime: time.Unix(1, 0)},
+	})
+}
+
+func TestManifestLoadError(t *testing.T) {
+	fail := false
+	h := &Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			if fail {
+				return nil, errors.New("gone")
+			}
+			return []byte("foo"), nil
+		}),
+	}
+	_, err := h.URL("a")
+	ensure.Nil(t, err)
+	h.cache().clear()
+	fail = true
+	var buf bytes.Buffer
+	ensure.Err(t, h.WriteManifest(&buf), regexp.MustCompile("gone"))
+}
+
+func TestLoadManifest(t *testing.T) {
+	src := &Handler{
+		Path: "/static/",
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	}
+	u, err := src.URL("a.css", "b.css")
+	ensure.Nil(t, err)
+	var buf bytes.Buffer
+	ensure.Nil(t, src.WriteManifest(&buf))
+
+	var reads int
+	dst := &Handler{
+		Path: "/static/",
+		Box: funcBox(func(name string) ([]byte, error) {
+			reads++
+			return []byte("foo"), nil
+		}),
+	}
+	ensure.Nil(t, dst.LoadManifest(&buf))
+	v, err := dst.URL("a.css", "b.css")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, u)
+	ensure.DeepEqual(t, reads, 0)
+
+	w := httptest.NewRecorder()
+	dst.ServeHTTP(w, httptest.NewRequest("GET", v, nil))
+	ensure.DeepEqual(t, w.Code, http.StatusOK)
+	ensure.DeepEqual(t, w.Body.String(), "foo\nfoo")
+}
+
+func TestLoadManifestInvalid(t *testing.T) {
+	var h Handler
+	ensure.NotNil(t, h.LoadManifest(strings.NewReader("x")))
+}
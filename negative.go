@@ -0,0 +1,64 @@
+package static
+
+import (
+	"sync"
+	"time"
+)
+
+// maxNegative bounds the negative cache, which is reset once it is full.
+const maxNegative = 1024
+
+type negativeEntry struct {
+	status  int
+	expires time.Time
+}
+
+// negativeCache remembers the status of failed requests. The zero value is an
+// empty cache.
+type negativeCache struct {
+	mu    sync.Mutex
+	items map[string]negativeEntry
+	now   func() time.Time // for tests
+}
+
+func (c *negativeCache) clock() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+	return time.Now()
+}
+
+func (c *negativeCache) get(key string) (int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, found := c.items[key]
+	if !found {
+		return 0, false
+	}
+	if c.clock().After(e.expires) {
+		delete(c.items, key)
+		return 0, false
+	}
+	return e.status, true
+}
+
+func (c *negativeCache) add(key string, status int, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.items == nil || len(c.items) >= maxNegative {
+		c.items = make(map[string]negativeEntry)
+	}
+	c.items[key] = negativeEntry{status: status, expires: c.clock().Add(ttl)}
+}
+
+func (c *negativeCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.items = nil
+}
+
+func (c *negativeCache) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.items, key)
+}
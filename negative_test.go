@@ -0,0 +1,68 @@
+package static
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestNegativeCacheExpires(t *testing.T) {
+	now := time.Unix(0, 0)
+	c := negativeCache{now: func() time.Time { return now }}
+	c.add("a", http.StatusGone, time.Minute)
+	status, found := c.get("a")
+	ensure.True(t, found)
+	ensure.DeepEqual(t, status, http.StatusGone)
+	now = now.Add(2 * time.Minute)
+	_, found = c.get("a")
+	ensure.False(t, found)
+}
+
+func TestNegativeCacheBounded(t *testing.T) {
+	var c negativeCache
+	for i := 0; i <= maxNegative; i++ {
+		c.add(strconv.Itoa(i), http.StatusNotFound, time.Minute)
+	}
+	ensure.DeepEqual(t, len(c.items), 1)
+}
+
+func TestServeNegativeCache(t *testing.T) {
+	var reads int
+	h := &Handler{
+		Path:             "/",
+		NegativeCacheTTL: time.Minute,
+		Box: funcBox(func(name string) ([]byte, error) {
+			reads++
+			return []byte("foo"), nil
+		}),
+	}
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/W1siZm9vIiwiYmFyIl1d", nil))
+		ensure.DeepEqual(t, w.Code, http.StatusNotFound)
+		ensureDisableCaching(t, w.Header())
+	}
+	ensure.DeepEqual(t, reads, 1)
+}
+
+func TestServeGoneStale(t *testing.T) {
+	h := &Handler{
+		Path:             "/",
+		GoneStale:        true,
+		NegativeCacheTTL: time.Minute,
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	}
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/W1siZm9vIiwiYmFyIl1d", nil))
+		ensure.DeepEqual(t, w.Code, http.StatusGone)
+		ensureDisableCaching(t, w.Header())
+		ensure.DeepEqual(t, w.Body.String(), http.StatusText(http.StatusGone))
+	}
+}
@@ -0,0 +1,129 @@
+package static
+
+import (
+	"bytes"
+	"image/jpeg"
+	"image/png"
+	"regexp"
+	"strings"
+)
+
+// OptimizePNG is a Transform which losslessly recompresses PNG images with the
+// best compression. The original is kept if it is already smaller.
+func OptimizePNG(name string, in []byte) ([]byte, error) {
+	img, err := png.Decode(bytes.NewReader(in))
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	enc := png.Encoder{CompressionLevel: png.BestCompression}
+	if err := enc.Encode(&out, img); err != nil {
+		return nil, err
+	}
+	return smaller(in, out.Bytes()), nil
+}
+
+// OptimizeJPEG returns a Transform which re-encodes JPEG images with the given
+// quality, from 1 to 100. The original is kept if it is already smaller.
+func OptimizeJPEG(quality int) Transform {
+	return func(name string, in []byte) ([]byte, error) {
+		img, err := jpeg.Decode(bytes.NewReader(in))
+		if err != nil {
+			return nil, err
+		}
+		var out bytes.Buffer
+		if err := jpeg.Encode(&out, img, &jpeg.Options{Quality: quality}); err != nil {
+			return nil, err
+		}
+		return smaller(in, out.Bytes()), nil
+	}
+}
+
+func smaller(original, optimized []byte) []byte {
+	if len(optimized) < len(original) {
+		return optimized
+	}
+	return original
+}
+
+// editorPrefixes are the namespace prefixes design tools use for their own
+// data in exported SVGs.
+const editorPrefixes = `(?:sodipodi|inkscape|sketch|serif|illustrator)`
+
+var svgCruft = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`<\?xml[\s\S]*?\?>`), ""},
+	{regexp.MustCompile(`<!DOCTYPE[^>\[]*(?:\[[\s\S]*?\])?\s*>`), ""},
+	{regexp.MustCompile(`<!--[\s\S]*?-->`), ""},
+	{regexp.MustCompile(`<metadata[\s\S]*?</metadata>`), ""},
+	{regexp.MustCompile(`<` + editorPrefixes + `:[\w.-]+[^>]*/>`), ""},
+	{regexp.MustCompile(`<(` + editorPrefixes + `:[\w.-]+)[^>]*>[\s\S]*?</(?:` + editorPrefixes + `:[\w.-]+)>`), ""},
+}
+
+var (
+	svgTag        = regexp.MustCompile(`<!\[CDATA\[[\s\S]*?\]\]>|</?([\w:.-]+)(?:[^>"']|"[^"]*"|'[^']*')*>`)
+	svgEditorAttr = regexp.MustCompile(`\s+(?:xmlns:)?` + editorPrefixes + `(?::[\w.-]+)?="[^"]*"`)
+	svgLineBreak  = regexp.MustCompile(`\s*\n\s*`)
+)
+
+// svgPreserved are the elements whose text is rendered or is code, so the
+// whitespace inside them is kept.
+var svgPreserved = map[string]bool{
+	"text":     true,
+	"tspan":    true,
+	"textPath": true,
+	"style":    true,
+	"script":   true,
+}
+
+// optimizeSVGTags strips editor attributes and line breaks from inside tags,
+// and drops line breaks between tags outside of text, styles and scripts.
+func optimizeSVGTags(in []byte) []byte {
+	var out bytes.Buffer
+	depth, last := 0, 0
+	for _, m := range svgTag.FindAllSubmatchIndex(in, -1) {
+		between := in[last:m[0]]
+		if depth > 0 || !bytes.Contains(between, []byte("\n")) || len(bytes.TrimSpace(between)) > 0 {
+			out.Write(between)
+		}
+		last = m[1]
+		tag := in[m[0]:m[1]]
+		if m[2] < 0 {
+			out.Write(tag)
+			continue
+		}
+		name := string(in[m[2]:m[3]])
+		if i := strings.LastIndexByte(name, ':'); i >= 0 {
+			name = name[i+1:]
+		}
+		if svgPreserved[name] {
+			switch {
+			case tag[1] == '/':
+				if depth > 0 {
+					depth--
+				}
+			case !bytes.HasSuffix(tag, []byte("/>")):
+				depth++
+			}
+		}
+		tag = svgEditorAttr.ReplaceAll(tag, nil)
+		out.Write(svgLineBreak.ReplaceAll(tag, []byte(" ")))
+	}
+	out.Write(in[last:])
+	return out.Bytes()
+}
+
+// OptimizeSVG is a Transform which strips the XML declaration, doctype,
+// comments, metadata, and elements and attributes of design tools such as
+// Inkscape from SVG images, along with line breaks between tags and
+// attributes. Whitespace in text, styles and scripts is kept.
+// It may be added with WithTransform(".svg", OptimizeSVG).
+func OptimizeSVG(name string, in []byte) ([]byte, error) {
+	out := in
+	for _, c := range svgCruft {
+		out = c.pattern.ReplaceAll(out, []byte(c.replacement))
+	}
+	return bytes.TrimSpace(optimizeSVGTags(out)), nil
+}
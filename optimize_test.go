@@ -0,0 +1,110 @@
+package static
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+)
+
+func testImage() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for x := 0; x < 64; x++ {
+		for y := 0; y < 64; y++ {
+			img.Set(x, y, color.RGBA{uint8(x * 4), uint8(y * 4), 0, 255})
+		}
+	}
+	return img
+}
+
+func TestOptimizePNG(t *testing.T) {
+	var buf bytes.Buffer
+	enc := png.Encoder{CompressionLevel: png.NoCompression}
+	ensure.Nil(t, enc.Encode(&buf, testImage()))
+	out, err := OptimizePNG("a.png", buf.Bytes())
+	ensure.Nil(t, err)
+	ensure.True(t, len(out) < buf.Len())
+	img, err := png.Decode(bytes.NewReader(out))
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, img.At(10, 20), testImage().At(10, 20))
+
+	again, err := OptimizePNG("a.png", out)
+	ensure.Nil(t, err)
+	ensure.True(t, len(again) <= len(out))
+}
+
+func TestOptimizeJPEG(t *testing.T) {
+	var buf bytes.Buffer
+	ensure.Nil(t, jpeg.Encode(&buf, testImage(), &jpeg.Options{Quality: 100}))
+	out, err := OptimizeJPEG(50)("a.jpg", buf.Bytes())
+	ensure.Nil(t, err)
+	ensure.True(t, len(out) < buf.Len())
+}
+
+func TestOptimizeInvalid(t *testing.T) {
+	_, err := OptimizePNG("a.png", []byte("foo"))
+	ensure.NotNil(t, err)
+	_, err = OptimizeJPEG(50)("a.jpg", []byte("foo"))
+	ensure.NotNil(t, err)
+}
+
+func TestWithImageOptimization(t *testing.T) {
+	h := New(WithImageOptimization(0))
+	ensure.DeepEqual(t, len(h.Transforms[".png"]), 1)
+	ensure.DeepEqual(t, len(h.Transforms[".jpg"]), 0)
+	h = New(WithImageOptimization(80))
+	ensure.DeepEqual(t, len(h.Transforms[".jpg"]), 1)
+	ensure.DeepEqual(t, len(h.Transforms[".jpeg"]), 1)
+}
+
+func TestOptimizeSVG(t *testing.T) {
+	in := `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<!-- Created with Inkscape (http://www.inkscape.org/) -->
+<!DOCTYPE svg PUBLIC "-//W3C//DTD SVG 1.1//EN" "http://www.w3.org/Graphics/SVG/1.1/DTD/svg11.dtd">
+<svg xmlns="http://www.w3.org/2000/svg"
+   xmlns:inkscape="http://www.inkscape.org/namespaces/inkscape"
+   xmlns:sodipodi="http://sodipodi.sourceforge.net/DTD/sodipodi-0.dtd"
+   viewBox="0 0 10 10" sodipodi:docname="icon.svg" inkscape:version="1.0">
+  <metadata><rdf:RDF><cc:Work/></rdf:RDF></metadata>
+  <sodipodi:namedview id="base" inkscape:zoom="1"/>
+  <sodipodi:guide position="1,1">x</sodipodi:guide>
+  <title>Icon</title>
+  <text><tspan>a</tspan> <tspan>b</tspan></text>
+  <text x="1"
+     inkscape:label="t">first line
+    inkscape:note="kept"
+    <tspan>second</tspan>
+  </text>
+  <style><![CDATA[
+    a > b { fill: red }
+  ]]></style>
+  <path inkscape:label="p" d="M0 0h10v10z"/>
+</svg>
+`
+	out, err := OptimizeSVG("icon.svg", []byte(in))
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, string(out),
+		`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 10 10">`+
+			`<title>Icon</title>`+
+			`<text><tspan>a</tspan> <tspan>b</tspan></text>`+
+			"<text x=\"1\">first line\n    inkscape:note=\"kept\"\n    <tspan>second</tspan>\n  </text>"+
+			"<style><![CDATA[\n    a > b { fill: red }\n  ]]></style>"+
+			`<path d="M0 0h10v10z"/>`+
+			`</svg>`)
+}
+
+func TestOptimizeSVGTransform(t *testing.T) {
+	h := New(
+		WithBox(funcBox(func(name string) ([]byte, error) {
+			return []byte("<!-- x --><svg/>"), nil
+		})),
+		WithTransform(".svg", OptimizeSVG),
+	)
+	v, err := h.ContentContext(makeCtx(h), "a.svg")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, string(v), "<svg/>")
+}
@@ -0,0 +1,158 @@
+package static
+
+import (
+	"hash"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Option configures a Handler created by New.
+type Option func(*Handler)
+
+// New returns a Handler configured with the given options. A Handler may also
+// be used directly as a struct literal, the options only set its fields.
+func New(options ...Option) *Handler {
+	h := &Handler{}
+	for _, o := range options {
+		o(h)
+	}
+	return h
+}
+
+// WithPath sets the Path at which the Handler is mounted.
+func WithPath(path string) Option {
+	return func(h *Handler) {
+		h.Path = path
+	}
+}
+
+// WithBox sets the Box the Handler serves files from.
+func WithBox(box Box) Option {
+	return func(h *Handler) {
+		h.Box = box
+	}
+}
+
+// WithMaxAge sets the max age for served files.
+func WithMaxAge(maxAge time.Duration) Option {
+	return func(h *Handler) {
+		h.MaxAge = maxAge
+	}
+}
+
+// WithLogger sets the Logger used to report load and serve failures.
+func WithLogger(logger *slog.Logger) Option {
+	return func(h *Handler) {
+		h.Logger = logger
+	}
+}
+
+// WithHashLen sets the number of hex characters of the hash used in URLs.
+func WithHashLen(n int) Option {
+	return func(h *Handler) {
+		h.HashLen = n
+	}
+}
+
+// WithHashFunc sets the hash used to fingerprint file contents. Any hash.Hash
+// may be used, such as sha256.New or a faster non-cryptographic hash.
+func WithHashFunc(newHash func() hash.Hash) Option {
+	return func(h *Handler) {
+		h.HashFunc = newHash
+	}
+}
+
+// WithCacheSize limits the total bytes of file contents kept in memory.
+func WithCacheSize(maxBytes int64) Option {
+	return func(h *Handler) {
+		h.CacheSize = maxBytes
+	}
+}
+
+// WithCacheTTL drops files from memory once they have gone unused for the
+// given duration.
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(h *Handler) {
+		h.CacheTTL = ttl
+	}
+}
+
+// WithHeaderFunc calls fn with the name of the first file before a response
+// is served, to set custom headers.
+func WithHeaderFunc(fn func(w http.ResponseWriter, name string)) Option {
+	return func(h *Handler) {
+		h.HeaderFunc = fn
+	}
+}
+
+// WithBaseURL sets the scheme and host used for absolute URLs.
+func WithBaseURL(base string) Option {
+	return func(h *Handler) {
+		h.BaseURL = base
+	}
+}
+
+// WithDev enables development mode, where files are not cached or hashed.
+func WithDev(dev bool) Option {
+	return func(h *Handler) {
+		h.Dev = dev
+	}
+}
+
+// WithTransform adds a Transform for files with the extension, such as ".js",
+// applied after those already added.
+func WithTransform(ext string, fn func(name string, in []byte) ([]byte, error)) Option {
+	return func(h *Handler) {
+		if h.Transforms == nil {
+			h.Transforms = make(map[string][]Transform)
+		}
+		ext = strings.ToLower(ext)
+		h.Transforms[ext] = append(h.Transforms[ext], fn)
+	}
+}
+
+// WithMinifier sets the Minifier for stylesheets and scripts.
+func WithMinifier(m Minifier) Option {
+	return func(h *Handler) {
+		h.Minifier = m
+	}
+}
+
+// WithCompiler sets the Compiler for files with the extension, such as ".ts".
+func WithCompiler(ext string, c Compiler) Option {
+	return func(h *Handler) {
+		if h.Compilers == nil {
+			h.Compilers = make(map[string]Compiler)
+		}
+		h.Compilers[strings.ToLower(ext)] = c
+	}
+}
+
+// WithImageOptimization adds Transforms recompressing PNG images losslessly,
+// and JPEG images with the given quality if it is not zero.
+func WithImageOptimization(jpegQuality int) Option {
+	return func(h *Handler) {
+		WithTransform(".png", OptimizePNG)(h)
+		if jpegQuality > 0 {
+			WithTransform(".jpg", OptimizeJPEG(jpegQuality))(h)
+			WithTransform(".jpeg", OptimizeJPEG(jpegQuality))(h)
+		}
+	}
+}
+
+// WithImageFormats sets the converters for alternative image formats, such as
+// WebP or AVIF, in order of preference.
+func WithImageFormats(formats ...Compiler) Option {
+	return func(h *Handler) {
+		h.ImageFormats = formats
+	}
+}
+
+// WithResizeWidths sets the widths images may be resized to.
+func WithResizeWidths(widths ...int) Option {
+	return func(h *Handler) {
+		h.ResizeWidths = widths
+	}
+}
@@ -0,0 +1,52 @@
+package static
+
+import (
+	"hash"
+	"hash/fnv"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestNewDefaults(t *testing.T) {
+	ensure.DeepEqual(t, New(), &Handler{})
+}
+
+func TestNewWithOptions(t *testing.T) {
+	box := funcBox(func(name string) ([]byte, error) {
+		return []byte(name), nil
+	})
+	h := New(
+		WithPath("/assets/"),
+		WithBox(box),
+		WithMaxAge(time.Hour),
+		WithLogger(slog.Default()),
+		WithHashLen(12),
+		WithCacheSize(1024),
+		WithCacheTTL(time.Minute),
+	)
+	ensure.DeepEqual(t, h.Path, "/assets/")
+	ensure.DeepEqual(t, h.MaxAge, time.Hour)
+	ensure.True(t, h.Logger == slog.Default())
+	ensure.DeepEqual(t, h.HashLen, 12)
+	ensure.DeepEqual(t, h.CacheSize, int64(1024))
+	ensure.DeepEqual(t, h.CacheTTL, time.Minute)
+	v, err := h.Box.Bytes("foo")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, string(v), "foo")
+}
+
+func TestWithHashFunc(t *testing.T) {
+	h := New(
+		WithHashFunc(func() hash.Hash { return fnv.New64a() }),
+		WithHashLen(-1),
+		WithBox(funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		})),
+	)
+	f, err := h.load("foo")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, f.Hash, "dcb27518fed9d577")
+}
@@ -0,0 +1,69 @@
+package static
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+func savedKey(name, hash string) string {
+	return name + "\x00" + hash
+}
+
+// SaveCache writes the cached files to path, so another process can serve
+// them using LoadCache. Files cached without their contents, as with
+// HashMetadata, are not included.
+func (h *Handler) SaveCache(path string) error {
+	var files []file
+	for _, f := range h.cache().files() {
+		if f.Content != nil {
+			files = append(files, f)
+		}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := json.NewEncoder(tmp).Encode(files); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadCache loads files written by SaveCache. They are used to serve URLs
+// whose hashes match them, even if the files have since changed, so URLs
+// issued before a restart or by another instance keep working.
+func (h *Handler) LoadCache(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var files []file
+	if err := json.NewDecoder(f).Decode(&files); err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.saved == nil {
+		h.saved = make(map[string]file, len(files))
+	}
+	for _, f := range files {
+		h.saved[savedKey(f.Name, f.Hash)] = f
+	}
+	return nil
+}
+
+func (h *Handler) lookupSaved(f file) (file, bool) {
+	h.mu.RLock()
+	saved, found := h.saved[savedKey(f.Name, f.Hash)]
+	h.mu.RUnlock()
+	return saved, found
+}
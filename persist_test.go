@@ -0,0 +1,65 @@
+package static
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestSaveLoadCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+	contents := "foo"
+	h := &Handler{
+		Path: "/",
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte(contents), nil
+		}),
+	}
+	v, err := h.URL("a.css")
+	ensure.Nil(t, err)
+	ensure.Nil(t, h.SaveCache(path))
+
+	// the file changed, so without the saved cache the old URL is stale
+	contents = "bar"
+	h2 := &Handler{
+		Path: "/",
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte(contents), nil
+		}),
+	}
+	w := httptest.NewRecorder()
+	h2.ServeHTTP(w, httptest.NewRequest("GET", v, nil))
+	ensure.DeepEqual(t, w.Code, http.StatusNotFound)
+
+	ensure.Nil(t, h2.LoadCache(path))
+	w = httptest.NewRecorder()
+	h2.ServeHTTP(w, httptest.NewRequest("GET", v, nil))
+	ensure.DeepEqual(t, w.Code, http.StatusOK)
+	ensure.DeepEqual(t, w.Body.String(), "foo")
+
+	// new URLs use the current contents
+	v2, err := h2.URL("a.css")
+	ensure.Nil(t, err)
+	ensure.NotDeepEqual(t, v2, v)
+}
+
+func TestLoadCacheMissing(t *testing.T) {
+	var h Handler
+	ensure.True(t, os.IsNotExist(h.LoadCache(filepath.Join(t.TempDir(), "missing"))))
+}
+
+func TestLoadCacheInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+	ensure.Nil(t, os.WriteFile(path, []byte("x"), 0644))
+	var h Handler
+	ensure.NotNil(t, h.LoadCache(path))
+}
+
+func TestSaveCacheError(t *testing.T) {
+	var h Handler
+	ensure.NotNil(t, h.SaveCache(filepath.Join(t.TempDir(), "missing", "cache.json")))
+}
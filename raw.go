@@ -0,0 +1,58 @@
+package static
+
+import (
+	"errors"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// rawName returns the name of the file for a path following Path, and
+// reports if it is under RawPath.
+func (h *Handler) rawName(rest string) (string, bool) {
+	if h.rawPath() == "" {
+		return "", false
+	}
+	rawPath := strings.Trim(h.rawPath(), "/") + "/"
+	if !strings.HasPrefix(rest, rawPath) {
+		return "", false
+	}
+	name := strings.TrimPrefix(path.Clean("/"+rest[len(rawPath):]), "/")
+	return name, name != ""
+}
+
+// errNoRawPath is returned for raw URLs when raw serving is not enabled.
+var errNoRawPath = errors.New("static: no RawPath for raw URL")
+
+// RawURL returns the unhashed URL for the named file under RawPath. It fails
+// if neither RawPath nor Dev is set, as such a URL would not be served.
+func (h *Handler) RawURL(name string) (string, error) {
+	if h.rawPath() == "" {
+		return "", errNoRawPath
+	}
+	return path.Join(h.prefix(), h.rawPath(), cleanName(name)), nil
+}
+
+// FileHandler returns a http.Handler which serves the current version of the
+// named file at any path, such as for "/favicon.ico" which browsers request
+// without a hash. It is served with RawMaxAge.
+func (h *Handler) FileHandler(name string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serve := func(w http.ResponseWriter, r *http.Request) {
+			files := []file{{Name: cleanName(name)}}
+			h.serveFiles(w, r, files, h.typeByExtension(path.Ext(name)), true, 0)
+		}
+		if h.AccessLog != nil {
+			h.serveLogged(w, r, serve)
+			return
+		}
+		serve(w, r)
+	})
+}
+
+func (h *Handler) rawCacheControl() string {
+	if h.RawMaxAge <= 0 || h.Dev {
+		return "no-cache"
+	}
+	return makeCacheControl(h.RawMaxAge)
+}
@@ -0,0 +1,110 @@
+package static
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestRawPath(t *testing.T) {
+	contents := "old"
+	h := &Handler{
+		Path:    "/static/",
+		RawPath: "raw/",
+		Box: funcBox(func(name string) ([]byte, error) {
+			if name != "css/app.css" {
+				return nil, &ErrAssetNotFound{Name: name}
+			}
+			return []byte(contents), nil
+		}),
+	}
+	u, err := h.RawURL("css/app.css")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, u, "/static/raw/css/app.css")
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", u, nil))
+	ensure.DeepEqual(t, w.Code, http.StatusOK)
+	ensure.DeepEqual(t, w.Body.String(), "old")
+	ensure.DeepEqual(t, w.Header().Get("Cache-Control"), "no-cache")
+	ensure.DeepEqual(t, w.Header().Get("Content-Type"), "text/css; charset=utf-8")
+	tag := w.Header().Get("ETag")
+
+	r := httptest.NewRequest("GET", u, nil)
+	r.Header.Set("If-None-Match", tag)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	ensure.DeepEqual(t, w.Code, http.StatusNotModified)
+	ensure.DeepEqual(t, w.Header().Get("Cache-Control"), "no-cache")
+
+	contents = "new"
+	h.ClearCache()
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/static/raw/css/../css/app.css", nil))
+	ensure.DeepEqual(t, w.Body.String(), "new")
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/static/raw/missing.css", nil))
+	ensure.DeepEqual(t, w.Code, http.StatusNotFound)
+}
+
+func TestRawMaxAge(t *testing.T) {
+	h := &Handler{
+		Path:      "/",
+		RawPath:   "raw",
+		RawMaxAge: time.Minute,
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	}
+	u, err := h.RawURL("a.js")
+	ensure.Nil(t, err)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", u, nil))
+	ensure.DeepEqual(t, w.Code, http.StatusOK)
+	ensure.DeepEqual(t, w.Header().Get("Cache-Control"), "public, max-age=60")
+}
+
+func TestRawURLWithoutRawPath(t *testing.T) {
+	h := &Handler{Path: "/static/"}
+	_, err := h.RawURL("css/app.css")
+	ensure.DeepEqual(t, err, errNoRawPath)
+
+	h.Dev = true
+	u, err := h.RawURL("css/app.css")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, u, "/static/dev/css/app.css")
+}
+
+func TestRawNameEscape(t *testing.T) {
+	h := &Handler{RawPath: "raw/"}
+	name, ok := h.rawName("raw/../../etc/passwd")
+	ensure.True(t, ok)
+	ensure.DeepEqual(t, name, "etc/passwd")
+	_, ok = h.rawName("raw/")
+	ensure.False(t, ok)
+	_, ok = h.rawName("W1siZm9vIiwiYmFyIl1d")
+	ensure.False(t, ok)
+}
+
+func TestFileHandler(t *testing.T) {
+	var logged []Access
+	h := &Handler{
+		Path: "/static/",
+		Box: funcBox(func(name string) ([]byte, error) {
+			ensure.DeepEqual(t, name, "img/favicon.ico")
+			return []byte("ico"), nil
+		}),
+		AccessLog: func(a Access) { logged = append(logged, a) },
+	}
+	w := httptest.NewRecorder()
+	h.FileHandler("img/favicon.ico").ServeHTTP(w, httptest.NewRequest("GET", "/favicon.ico", nil))
+	ensure.DeepEqual(t, w.Code, http.StatusOK)
+	ensure.DeepEqual(t, w.Body.String(), "ico")
+	ensure.DeepEqual(t, w.Header().Get("Cache-Control"), "no-cache")
+	ensure.DeepEqual(t, w.Header().Get("Content-Type"), "image/x-icon")
+	ensure.DeepEqual(t, len(logged), 1)
+}
@@ -0,0 +1,161 @@
+package static
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// resizePrefix starts the path segment holding the width of resized images,
+// such as "w=640/" in "/static/w=640/W1s....png".
+const resizePrefix = "w="
+
+// ResizeResolver is a Resolver which also provides URLs for resized images.
+type ResizeResolver interface {
+	Resolver
+	ResizedURLContext(ctx context.Context, name string, width int) (string, error)
+}
+
+var _ ResizeResolver = (*Handler)(nil)
+
+// ResizedURLContext returns the hashed URL for the image resized to the width,
+// which must be one of the ResizeWidths.
+func (h *Handler) ResizedURLContext(ctx context.Context, name string, width int) (string, error) {
+	if !h.resizeWidthAllowed(width) {
+		return "", fmt.Errorf("static: width %d is not one of the ResizeWidths", width)
+	}
+	if !resizable(name) {
+		return "", fmt.Errorf("static: %s cannot be resized", name)
+	}
+	u, err := h.URLContext(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	prefix := h.prefix()
+	return prefix + resizePrefix + strconv.Itoa(width) + "/" + strings.TrimPrefix(u, prefix), nil
+}
+
+// ResizedURL returns the hashed URL for the image resized to the width using the
+// Resolver in the context. It returns the URL of the original image if the
+// Resolver does not resize images.
+func ResizedURL(ctx context.Context, name string, width int) (string, error) {
+	r, ok := FromContext(ctx).(ResizeResolver)
+	if !ok {
+		return URL(ctx, name)
+	}
+	return r.ResizedURLContext(ctx, name, width)
+}
+
+// resizable reports if the file is an image which can be resized.
+func resizable(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".png", ".jpg", ".jpeg":
+		return true
+	}
+	return false
+}
+
+func (h *Handler) resizeWidthAllowed(width int) bool {
+	for _, w := range h.ResizeWidths {
+		if w == width && w > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// resizeWidth returns the width from the start of the path following Path,
+// along with the rest of the path. The width is zero if there is none.
+func (h *Handler) resizeWidth(rest string) (int, string, error) {
+	if len(h.ResizeWidths) == 0 || !strings.HasPrefix(rest, resizePrefix) {
+		return 0, rest, nil
+	}
+	segment, remaining, found := strings.Cut(rest[len(resizePrefix):], "/")
+	width, err := strconv.Atoi(segment)
+	if !found || err != nil || !h.resizeWidthAllowed(width) {
+		return 0, "", errInvalidURL(rest)
+	}
+	return width, remaining, nil
+}
+
+// resizer is the Compiler for an image resized to a width.
+type resizer struct {
+	width       int
+	contentType string
+}
+
+// Compile resizes the PNG or JPEG image, keeping its aspect ratio. Images no
+// wider than the width are returned as is.
+func (r *resizer) Compile(name string, in []byte) ([]byte, error) {
+	src, format, err := image.Decode(bytes.NewReader(in))
+	if err != nil {
+		return nil, err
+	}
+	b := src.Bounds()
+	if b.Dx() <= r.width {
+		return in, nil
+	}
+	height := b.Dy() * r.width / b.Dx()
+	if height < 1 {
+		height = 1
+	}
+	dst := resize(src, r.width, height)
+	var out bytes.Buffer
+	if format == "jpeg" {
+		err = jpeg.Encode(&out, dst, &jpeg.Options{Quality: 85})
+	} else {
+		err = png.Encode(&out, dst)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// ContentType returns the type of the original image.
+func (r *resizer) ContentType() string {
+	return r.contentType
+}
+
+// resize scales the image down to width by height, averaging the source
+// pixels covered by each destination pixel.
+func resize(src image.Image, width, height int) *image.NRGBA {
+	b := src.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := b.Min.Y + y*b.Dy()/height
+		y1 := b.Min.Y + (y+1)*b.Dy()/height
+		for x := 0; x < width; x++ {
+			x0 := b.Min.X + x*b.Dx()/width
+			x1 := b.Min.X + (x+1)*b.Dx()/width
+			var cr, cg, cb, ca, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					c := color.NRGBA64Model.Convert(src.At(sx, sy)).(color.NRGBA64)
+					cr += uint64(c.R)
+					cg += uint64(c.G)
+					cb += uint64(c.B)
+					ca += uint64(c.A)
+					n++
+				}
+			}
+			if n == 0 {
+				continue
+			}
+			dst.Set(x, y, color.NRGBA64{
+				R: uint16(cr / n),
+				G: uint16(cg / n),
+				B: uint16(cb / n),
+				A: uint16(ca / n),
+			})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,101 @@
+package static
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestResizedURL(t *testing.T) {
+	original := pngBytes(t, 40, 20)
+	h := &Handler{
+		Path:         "/static/",
+		ResizeWidths: []int{10, 80},
+		Box: funcBox(func(name string) ([]byte, error) {
+			return original, nil
+		}),
+	}
+	u, err := ResizedURL(makeCtx(h), "a.png", 10)
+	ensure.Nil(t, err)
+	plain, err := h.URL("a.png")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, u, "/static/w=10/"+plain[len("/static/"):])
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", u, nil))
+	ensure.DeepEqual(t, w.Code, 200)
+	ensure.DeepEqual(t, w.Header().Get("Content-Type"), "image/png")
+	config, err := png.DecodeConfig(bytes.NewReader(w.Body.Bytes()))
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, [2]int{config.Width, config.Height}, [2]int{10, 5})
+	ensure.StringContains(t, w.Header().Get("ETag"), "-w10")
+
+	r := httptest.NewRequest("GET", u, nil)
+	r.Header.Set("If-None-Match", w.Header().Get("ETag"))
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	ensure.DeepEqual(t, w.Code, 304)
+
+	// images are not enlarged
+	u, err = ResizedURL(makeCtx(h), "a.png", 80)
+	ensure.Nil(t, err)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", u, nil))
+	ensure.DeepEqual(t, w.Body.Bytes(), original)
+}
+
+func TestResizedURLErrors(t *testing.T) {
+	h := &Handler{
+		Path:         "/static/",
+		ResizeWidths: []int{10},
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	}
+	_, err := h.ResizedURLContext(context.Background(), "a.png", 20)
+	ensure.Err(t, err, regexp.MustCompile("not one of the ResizeWidths"))
+	_, err = h.ResizedURLContext(context.Background(), "a.gif", 10)
+	ensure.Err(t, err, regexp.MustCompile("cannot be resized"))
+
+	// images which cannot be decoded are served unchanged
+	plain, err := h.URL("a.png")
+	ensure.Nil(t, err)
+	u, err := ResizedURL(makeCtx(h), "a.png", 10)
+	ensure.Nil(t, err)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", u, nil))
+	ensure.DeepEqual(t, w.Code, 200)
+	ensure.DeepEqual(t, w.Body.String(), "foo")
+	ensure.DeepEqual(t, w.Header().Get("ETag"), etag([]file{{Name: "a.png", Hash: "acbd18db"}}))
+
+	for _, u := range []string{"/static/w=20/", "/static/w=x/", "/static/w=10"} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", u+plain[len("/static/"):], nil))
+		ensure.DeepEqual(t, w.Code, 400, u)
+	}
+}
+
+func TestResizedURLUnsupportedResolver(t *testing.T) {
+	v, err := ResizedURL(NewContext(context.Background(), urlResolver{}), "a.png", 10)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, "url")
+}
+
+func TestResize(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+	for i := range src.Pix {
+		src.Pix[i] = 255
+	}
+	src.Pix[0], src.Pix[1], src.Pix[2] = 0, 0, 0
+	dst := resize(src, 2, 1)
+	ensure.DeepEqual(t, dst.Bounds(), image.Rect(0, 0, 2, 1))
+	ensure.DeepEqual(t, dst.Pix[:4], []uint8{191, 191, 191, 255})
+	ensure.DeepEqual(t, dst.Pix[4:], []uint8{255, 255, 255, 255})
+}
@@ -0,0 +1,88 @@
+package static
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// shardedCache spreads files over a number of fileCaches by the hash of their
+// name, so lookups for different files rarely contend on the same lock.
+type shardedCache struct {
+	once   sync.Once
+	shards []fileCache
+}
+
+// cache returns the file cache, creating the shards on first use.
+func (h *Handler) cache() *shardedCache {
+	h.memory.once.Do(func() {
+		n := h.CacheShards
+		if n < 1 {
+			n = 1
+		}
+		h.memory.shards = make([]fileCache, n)
+	})
+	return &h.memory
+}
+
+func (c *shardedCache) shard(name string) *fileCache {
+	if len(c.shards) == 1 {
+		return &c.shards[0]
+	}
+	hs := fnv.New32a()
+	hs.Write([]byte(name))
+	return &c.shards[hs.Sum32()%uint32(len(c.shards))]
+}
+
+func (c *shardedCache) get(name string, ttl time.Duration) (file, bool) {
+	return c.shard(name).get(name, ttl)
+}
+
+// add caches the file, with each shard limited to an even part of maxBytes.
+func (c *shardedCache) add(f file, maxBytes int64, ttl time.Duration) []file {
+	if maxBytes > 0 {
+		maxBytes /= int64(len(c.shards))
+		if maxBytes == 0 {
+			maxBytes = 1
+		}
+	}
+	return c.shard(f.Name).add(f, maxBytes, ttl)
+}
+
+func (c *shardedCache) remove(name string) bool {
+	return c.shard(name).remove(name)
+}
+
+func (c *shardedCache) clear() {
+	for i := range c.shards {
+		c.shards[i].clear()
+	}
+}
+
+func (c *shardedCache) files() []file {
+	var files []file
+	for i := range c.shards {
+		files = append(files, c.shards[i].files()...)
+	}
+	return files
+}
+
+func (c *shardedCache) stats() (int64, int, int64) {
+	var evictions, size int64
+	var entries int
+	for i := range c.shards {
+		e, n, s := c.shards[i].stats()
+		evictions += e
+		entries += n
+		size += s
+	}
+	return evictions, entries, size
+}
+
+func (c *shardedCache) len() int {
+	var n int
+	for i := range c.shards {
+		n += c.shards[i].len()
+	}
+	return n
+}
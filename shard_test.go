@@ -0,0 +1,79 @@
+package static
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestShardedCache(t *testing.T) {
+	h := &Handler{CacheShards: 4}
+	c := h.cache()
+	ensure.DeepEqual(t, len(c.shards), 4)
+	for i := 0; i < 20; i++ {
+		c.add(file{Name: fmt.Sprint(i), Content: []byte("x")}, 0, 0)
+	}
+	ensure.DeepEqual(t, c.len(), 20)
+	ensure.DeepEqual(t, len(c.files()), 20)
+	_, entries, size := c.stats()
+	ensure.DeepEqual(t, entries, 20)
+	ensure.DeepEqual(t, size, int64(20))
+
+	f, found := c.get("3", 0)
+	ensure.True(t, found)
+	ensure.DeepEqual(t, f.Name, "3")
+	ensure.True(t, c.remove("3"))
+	ensure.False(t, c.remove("3"))
+
+	var used int
+	for i := range c.shards {
+		if c.shards[i].len() > 0 {
+			used++
+		}
+	}
+	ensure.True(t, used > 1)
+
+	c.clear()
+	ensure.DeepEqual(t, c.len(), 0)
+}
+
+func TestShardedCacheSize(t *testing.T) {
+	h := &Handler{CacheShards: 2}
+	c := h.cache()
+	for i := 0; i < 10; i++ {
+		c.add(file{Name: fmt.Sprint(i), Content: []byte("xx")}, 8, 0)
+	}
+	_, _, size := c.stats()
+	ensure.True(t, size <= 8)
+}
+
+func TestShardedCacheDefault(t *testing.T) {
+	h := &Handler{}
+	ensure.DeepEqual(t, len(h.cache().shards), 1)
+}
+
+func benchmarkLoad(b *testing.B, shards int) {
+	h := &Handler{
+		CacheShards: shards,
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte(name), nil
+		}),
+	}
+	names := make([]string, 64)
+	for i := range names {
+		names[i] = fmt.Sprintf("file%d.js", i)
+		h.load(names[i])
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		var i int
+		for pb.Next() {
+			h.load(names[i%len(names)])
+			i++
+		}
+	})
+}
+
+func BenchmarkLoadOneShard(b *testing.B)      { benchmarkLoad(b, 1) }
+func BenchmarkLoadSixteenShards(b *testing.B) { benchmarkLoad(b, 16) }
@@ -0,0 +1,48 @@
+package static
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+)
+
+// ErrCacheMiss is returned by a Cache when the key is not found.
+var ErrCacheMiss = errors.New("static: cache miss")
+
+// Cache is a store of file contents, such as Redis, memcached or groupcache,
+// which can be shared by several instances. Keys are safe for use with all
+// of these.
+type Cache interface {
+	Get(key string) ([]byte, error)
+	Set(key string, value []byte) error
+	Delete(key string) error
+}
+
+func cacheKey(name, hash string) string {
+	sum := sha256.Sum256([]byte(name + "\x00" + hash))
+	return "static:" + hex.EncodeToString(sum[:])
+}
+
+func (h *Handler) storeShared(f file) {
+	if h.Cache == nil || f.Content == nil {
+		return
+	}
+	if err := h.Cache.Set(cacheKey(f.Name, f.Hash), f.Content); err != nil {
+		h.warn("static: cache set failed", "name", f.Name, "err", err)
+	}
+}
+
+func (h *Handler) lookupShared(f file) (file, bool) {
+	if h.Cache == nil {
+		return file{}, false
+	}
+	contents, err := h.Cache.Get(cacheKey(f.Name, f.Hash))
+	if err != nil {
+		if err != ErrCacheMiss {
+			h.warn("static: cache get failed", "name", f.Name, "err", err)
+		}
+		return file{}, false
+	}
+	f.Content = contents
+	return f, true
+}
@@ -0,0 +1,81 @@
+package static
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+)
+
+type mapCache map[string][]byte
+
+func (m mapCache) Get(key string) ([]byte, error) {
+	v, found := m[key]
+	if !found {
+		return nil, ErrCacheMiss
+	}
+	return v, nil
+}
+
+func (m mapCache) Set(key string, value []byte) error {
+	m[key] = value
+	return nil
+}
+
+func (m mapCache) Delete(key string) error {
+	delete(m, key)
+	return nil
+}
+
+type errCache struct{}
+
+func (errCache) Get(key string) ([]byte, error)     { return nil, errors.New("get") }
+func (errCache) Set(key string, value []byte) error { return errors.New("set") }
+func (errCache) Delete(key string) error            { return errors.New("delete") }
+
+func TestSharedCache(t *testing.T) {
+	shared := mapCache{}
+	h1 := &Handler{
+		Path:  "/",
+		Cache: shared,
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("v1"), nil
+		}),
+	}
+	v, err := h1.URL("a.css")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, len(shared), 1)
+
+	// another instance with newer contents can still serve the old URL
+	h2 := &Handler{
+		Path:  "/",
+		Cache: shared,
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("v2"), nil
+		}),
+	}
+	w := httptest.NewRecorder()
+	h2.ServeHTTP(w, httptest.NewRequest("GET", v, nil))
+	ensure.DeepEqual(t, w.Code, http.StatusOK)
+	ensure.DeepEqual(t, w.Body.String(), "v1")
+}
+
+func TestSharedCacheErrors(t *testing.T) {
+	h := &Handler{
+		Path:  "/",
+		Cache: errCache{},
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	}
+	v, err := h.URL("a.css")
+	ensure.Nil(t, err)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", v, nil))
+	ensure.DeepEqual(t, w.Code, http.StatusOK)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/W1siZm9vIiwiYmFyIl1d", nil))
+	ensure.DeepEqual(t, w.Code, http.StatusNotFound)
+}
@@ -0,0 +1,137 @@
+package static
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// sourceMapSuffix is appended to the URL of combined scripts for their index
+// source map.
+const sourceMapSuffix = ".map"
+
+// indexSourceMap is a version 3 source map made of a section per file.
+type indexSourceMap struct {
+	Version  int             `json:"version"`
+	File     string          `json:"file,omitempty"`
+	Sections []sourceSection `json:"sections"`
+}
+
+type sourceSection struct {
+	Offset struct {
+		Line   int `json:"line"`
+		Column int `json:"column"`
+	} `json:"offset"`
+	Map json.RawMessage `json:"map"`
+}
+
+// sourceMapped reports if the files get a source map, which is the case for
+// combined scripts when SourceMaps is set.
+func (h *Handler) sourceMapped(files []file) bool {
+	if !h.SourceMaps || len(files) < 2 || streamed(files) {
+		return false
+	}
+	ext := h.outputExt(files[0].Name)
+	return ext == ".js" || ext == ".mjs"
+}
+
+// sourceMapTrailer returns the sourceMappingURL comment for combined scripts,
+// or nil.
+func (h *Handler) sourceMapTrailer(files []file) []byte {
+	if !h.sourceMapped(files) {
+		return nil
+	}
+	u, err := h.makeURL(files)
+	if err != nil {
+		return nil
+	}
+	return []byte("\n//# sourceMappingURL=" + path.Base(u) + sourceMapSuffix + "\n")
+}
+
+// sourceMapRequest returns the files whose source map is requested by rest, the
+// path following Path.
+func (h *Handler) sourceMapRequest(rest string) ([]file, bool) {
+	if !h.SourceMaps || !strings.HasSuffix(rest, sourceMapSuffix) {
+		return nil, false
+	}
+	rest = strings.TrimSuffix(rest, sourceMapSuffix)
+	files, err := decode(strings.TrimSuffix(rest, path.Ext(rest)))
+	if err != nil {
+		return nil, false
+	}
+	return files, h.sourceMapped(files)
+}
+
+// serveSourceMap serves the index source map for the combined files.
+func (h *Handler) serveSourceMap(w http.ResponseWriter, r *http.Request, files []file) {
+	for _, f := range files {
+		if err := validName(f.Name); err != nil {
+			h.warn("static: bad request", "path", r.URL.Path, "err", err)
+			badRequest(w)
+			return
+		}
+	}
+	if err := h.resolve(files); err != nil {
+		h.warn("static: not found", "path", r.URL.Path, "err", err)
+		h.writeFailure(w, r, h.failureStatus(err))
+		return
+	}
+	contents, err := json.Marshal(h.sourceMap(files))
+	if err != nil {
+		h.warn("static: source map failed", "path", r.URL.Path, "err", err)
+		h.writeFailure(w, r, http.StatusNotFound)
+		return
+	}
+	h.securityHeaders(w)
+	h.writeHeaders(w, int64(len(contents)), "application/json")
+	w.Write(contents)
+}
+
+// sourceMap returns the index source map for the files, using the existing
+// map for each file, named like "app.js.map", or generating one mapping each
+// line to itself. Existing maps are ignored for transformed files, as they
+// describe the contents before the transform.
+func (h *Handler) sourceMap(files []file) indexSourceMap {
+	m := indexSourceMap{Version: 3, Sections: make([]sourceSection, 0, len(files))}
+	sep := h.separator(files[0].Name)
+	var line int
+	for _, f := range files {
+		var s sourceSection
+		s.Offset.Line = line
+		s.Map = identitySourceMap(f.Name, f.Content)
+		if !h.transformed(f.Name) {
+			if existing, err := h.boxBytes(f.Name + sourceMapSuffix); err == nil && json.Valid(existing) {
+				s.Map = existing
+			}
+		}
+		m.Sections = append(m.Sections, s)
+		line += bytes.Count(f.Content, []byte("\n")) + bytes.Count(sep, []byte("\n"))
+	}
+	return m
+}
+
+// identitySourceMap returns a source map mapping each line of the generated
+// file to the same line of the source.
+func identitySourceMap(name string, contents []byte) json.RawMessage {
+	lines := bytes.Count(contents, []byte("\n")) + 1
+	mappings := make([]string, lines)
+	for i := range mappings {
+		// the first segment is at column 0 of source 0, line 0, and each
+		// following one advances the source line by one.
+		if i == 0 {
+			mappings[i] = "AAAA"
+		} else {
+			mappings[i] = "AACA"
+		}
+	}
+	v, _ := json.Marshal(struct {
+		Version        int      `json:"version"`
+		Sources        []string `json:"sources"`
+		SourcesContent []string `json:"sourcesContent"`
+		Names          []string `json:"names"`
+		Mappings       string   `json:"mappings"`
+	}{3, []string{name}, []string{string(contents)}, []string{}, strings.Join(mappings, ";")})
+	return v
+}
@@ -0,0 +1,143 @@
+package static
+
+import (
+	"encoding/json"
+	"io/fs"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestSourceMap(t *testing.T) {
+	contents := map[string]string{
+		"a.js":     "a()\nb()",
+		"b.js":     "c()",
+		"b.js.map": `{"version":3,"sources":["b.ts"],"mappings":"AAAA"}`,
+	}
+	h := &Handler{
+		Path:       "/static/",
+		SourceMaps: true,
+		Box: funcBox(func(name string) ([]byte, error) {
+			if c, found := contents[name]; found {
+				return []byte(c), nil
+			}
+			return nil, fs.ErrNotExist
+		}),
+	}
+	u, err := h.URL("a.js", "b.js")
+	ensure.Nil(t, err)
+	w := serveURL(t, h, nil, "a.js", "b.js")
+	trailer := "\n//# sourceMappingURL=" + strings.TrimPrefix(u, "/static/") + ".map\n"
+	ensure.DeepEqual(t, w.Body.String(), "a()\nb()\n;\nc()"+trailer)
+
+	content, err := h.ContentContext(makeCtx(h), "a.js", "b.js")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, string(content), w.Body.String())
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", u+".map", nil))
+	ensure.DeepEqual(t, w.Code, 200)
+	ensure.DeepEqual(t, w.Header().Get("Content-Type"), "application/json")
+	var m struct {
+		Version  int
+		Sections []struct {
+			Offset struct{ Line int }
+			Map    struct {
+				Sources  []string
+				Mappings string
+			}
+		}
+	}
+	ensure.Nil(t, json.Unmarshal(w.Body.Bytes(), &m))
+	ensure.DeepEqual(t, m.Version, 3)
+	ensure.DeepEqual(t, len(m.Sections), 2)
+	ensure.DeepEqual(t, m.Sections[0].Offset.Line, 0)
+	ensure.DeepEqual(t, m.Sections[0].Map.Sources, []string{"a.js"})
+	ensure.DeepEqual(t, m.Sections[0].Map.Mappings, "AAAA;AACA")
+	ensure.DeepEqual(t, m.Sections[1].Offset.Line, 3)
+	ensure.DeepEqual(t, m.Sections[1].Map.Sources, []string{"b.ts"})
+}
+
+func TestSourceMapMinified(t *testing.T) {
+	contents := map[string]string{
+		"a.js":     "a ( )",
+		"a.js.map": `{"version":3,"sources":["a.ts"],"mappings":"AAAA"}`,
+		"b.js":     "b()",
+	}
+	h := &Handler{
+		Path:       "/static/",
+		SourceMaps: true,
+		Minifier:   &spaceMinifier{},
+		Box: funcBox(func(name string) ([]byte, error) {
+			if c, found := contents[name]; found {
+				return []byte(c), nil
+			}
+			return nil, fs.ErrNotExist
+		}),
+	}
+	u, err := h.URL("a.js", "b.js")
+	ensure.Nil(t, err)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", u+".map", nil))
+	ensure.DeepEqual(t, w.Code, 200)
+	var m struct {
+		Sections []struct {
+			Map struct {
+				Sources        []string
+				SourcesContent []string
+			}
+		}
+	}
+	ensure.Nil(t, json.Unmarshal(w.Body.Bytes(), &m))
+	ensure.DeepEqual(t, m.Sections[0].Map.Sources, []string{"a.js"})
+	ensure.DeepEqual(t, m.Sections[0].Map.SourcesContent, []string{"a()"})
+}
+
+func TestSourceMapDisabled(t *testing.T) {
+	h := &Handler{
+		Path: "/static/",
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("a()"), nil
+		}),
+	}
+	w := serveURL(t, h, nil, "a.js", "b.js")
+	ensure.DeepEqual(t, w.Body.String(), "a()\n;\na()")
+
+	u, err := h.URL("a.js", "b.js")
+	ensure.Nil(t, err)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", u+".map", nil))
+	ensure.DeepEqual(t, w.Code, 400)
+}
+
+func TestSourceMapSingleFile(t *testing.T) {
+	h := &Handler{
+		Path:       "/static/",
+		SourceMaps: true,
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("a()"), nil
+		}),
+	}
+	w := serveURL(t, h, nil, "a.js")
+	ensure.DeepEqual(t, w.Body.String(), "a()")
+}
+
+func TestSourceMapStale(t *testing.T) {
+	contents := "a()"
+	h := &Handler{
+		Path:       "/static/",
+		SourceMaps: true,
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte(contents), nil
+		}),
+	}
+	u, err := h.URL("a.js", "b.js")
+	ensure.Nil(t, err)
+	contents = "b()"
+	h.ClearCache()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", u+".map", nil))
+	ensure.DeepEqual(t, w.Code, 404)
+}
@@ -1,41 +1,54 @@
-// Package static provides go.h compatible hashed static assets. This allows
-// for providing long lived cache headers for resources which change URLs as
-// their content changes.
+// Package static provides hashed static assets. This allows for providing long
+// lived cache headers for resources which change URLs as their content
+// changes. The go.h components using these URLs are in the h subpackage.
 package static
 
 import (
 	"bytes"
 	"context"
 	"crypto/md5"
+	"embed"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"io/fs"
 	"io/ioutil"
-	"mime"
+	"log/slog"
 	"net/http"
 	"path"
-	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/daaku/go.h"
+	"github.com/fsnotify/fsnotify"
+	"golang.org/x/sync/singleflight"
 )
 
 const (
-	maxAge  = time.Hour * 24 * 365 * 10
-	hashLen = 8
+	defaultMaxAge  = time.Hour * 24 * 365 * 10
+	defaultHashLen = 8
 )
 
+// ErrNotConfigured is returned when a Handler without a Box is asked for a file.
+var ErrNotConfigured = errors.New("static: no Box configured")
+
 var (
 	errZeroNames          = errors.New("static: zero names given")
 	errNoHandlerInContext = errors.New("static: no handler in context")
-	cacheControl          = fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds()))
+	errNoStat             = errors.New("static: box does not support stat")
+	defaultCacheControl   = makeCacheControl(defaultMaxAge)
 )
 
+func makeCacheControl(maxAge time.Duration) string {
+	return fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds()))
+}
+
 func disableCaching(w http.ResponseWriter) {
 	header := w.Header()
 	header.Set("Cache-Control", "no-cache")
@@ -54,12 +67,77 @@ func badRequest(w http.ResponseWriter) {
 	io.WriteString(w, http.StatusText(http.StatusBadRequest))
 }
 
+func serviceUnavailable(w http.ResponseWriter) {
+	disableCaching(w)
+	w.WriteHeader(http.StatusServiceUnavailable)
+	io.WriteString(w, http.StatusText(http.StatusServiceUnavailable))
+}
+
+func gone(w http.ResponseWriter) {
+	disableCaching(w)
+	w.WriteHeader(http.StatusGone)
+	io.WriteString(w, http.StatusText(http.StatusGone))
+}
+
+// writeFailure responds with the status for a missing or stale asset, using
+// NotFound if it is set for 404 Not Found.
+func (h *Handler) writeFailure(w http.ResponseWriter, r *http.Request, status int) {
+	w.Header().Del("ETag")
+	if status == http.StatusGone {
+		gone(w)
+		return
+	}
+	if h.NotFound != nil {
+		disableCaching(w)
+		h.NotFound.ServeHTTP(w, r)
+		return
+	}
+	notFound(w)
+}
+
 type errInvalidURL string
 
 func (e errInvalidURL) Error() string {
 	return fmt.Sprintf("static: invalid URL %q", string(e))
 }
 
+// ErrAssetNotFound is returned when a named file does not exist in the Box.
+type ErrAssetNotFound struct {
+	Name string
+	Err  error
+}
+
+func (e *ErrAssetNotFound) Error() string {
+	return fmt.Sprintf("static: asset %q not found", e.Name)
+}
+
+// Unwrap returns the underlying Box error.
+func (e *ErrAssetNotFound) Unwrap() error {
+	return e.Err
+}
+
+// ErrInvalidName is returned for names which are not allowed, such as those
+// referring to a parent directory.
+type ErrInvalidName struct {
+	Name   string
+	Reason string
+}
+
+func (e *ErrInvalidName) Error() string {
+	return fmt.Sprintf("static: invalid name %q: %s", e.Name, e.Reason)
+}
+
+// ErrStaleHash is returned when a requested hash no longer matches the
+// contents of the named file.
+type ErrStaleHash struct {
+	Name string
+	Hash string
+}
+
+func (e *ErrStaleHash) Error() string {
+	return fmt.Sprintf("static: stale hash %q for asset %q", e.Hash, e.Name)
+}
+
 // we drop base64 padding in our URLs
 func dropPadding(s string) string {
 	return strings.TrimRight(s, "=")
@@ -80,9 +158,23 @@ func addPadding(s string) string {
 }
 
 type file struct {
-	Name    string
-	Content []byte
-	Hash    string
+	Name        string
+	Content     []byte
+	Encoded     map[string][]byte // Compressed contents by content coding.
+	ContentType string
+	Streamed    int64 // Size of contents served directly from the Box.
+	Hash        string
+	ModTime     time.Time
+	Deps        map[string]time.Time // Modification times of files the contents depend on.
+}
+
+// size returns the number of bytes held for the file.
+func (f file) size() int64 {
+	n := len(f.Content)
+	for _, encoded := range f.Encoded {
+		n += len(encoded)
+	}
+	return int64(n)
 }
 
 func encode(files []file) (string, error) {
@@ -130,6 +222,21 @@ type Box interface {
 	Bytes(name string) ([]byte, error)
 }
 
+// StatBox is a Box which can also provide file metadata. The Boxes returned by
+// FileSystemBox and FSBox implement it.
+type StatBox interface {
+	Box
+	Stat(name string) (fs.FileInfo, error)
+}
+
+// OpenBox is a StatBox which can also open files for reading, allowing large
+// files to be streamed. The Boxes returned by FileSystemBox, FSBox and
+// OverlayBox implement it.
+type OpenBox interface {
+	StatBox
+	Open(name string) (io.ReadCloser, error)
+}
+
 type fileSystemBox struct {
 	fs http.FileSystem
 }
@@ -143,68 +250,648 @@ func (b *fileSystemBox) Bytes(name string) ([]byte, error) {
 	return ioutil.ReadAll(f)
 }
 
+func (b *fileSystemBox) Open(name string) (io.ReadCloser, error) {
+	return b.fs.Open(name)
+}
+
+func (b *fileSystemBox) Stat(name string) (fs.FileInfo, error) {
+	f, err := b.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return f.Stat()
+}
+
 // FileSystemBox returns a Box from a http.FileSystem.
-func FileSystemBox(fs http.FileSystem) Box {
+func FileSystemBox(fs http.FileSystem) OpenBox {
 	return &fileSystemBox{fs: fs}
 }
 
-// Handler serves and provides URLs for static resources.
+type fsBox struct {
+	fs fs.FS
+}
+
+func (b *fsBox) Bytes(name string) ([]byte, error) {
+	return fs.ReadFile(b.fs, strings.TrimPrefix(name, "/"))
+}
+
+func (b *fsBox) Open(name string) (io.ReadCloser, error) {
+	return b.fs.Open(strings.TrimPrefix(name, "/"))
+}
+
+func (b *fsBox) Stat(name string) (fs.FileInfo, error) {
+	return fs.Stat(b.fs, strings.TrimPrefix(name, "/"))
+}
+
+// FSBox returns a Box from a fs.FS, such as an embed.FS or fstest.MapFS.
+func FSBox(fsys fs.FS) OpenBox {
+	return &fsBox{fs: fsys}
+}
+
+// EmbedBox returns a Box from an embed.FS rooted at the given directory, so
+// names need not include the embedded path prefix.
+func EmbedBox(efs embed.FS, root string) (Box, error) {
+	sub, err := fs.Sub(efs, root)
+	if err != nil {
+		return nil, err
+	}
+	return FSBox(sub), nil
+}
+
+type overlayBox []Box
+
+func (o overlayBox) Bytes(name string) ([]byte, error) {
+	for _, b := range o {
+		contents, err := b.Bytes(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		return contents, err
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+func (o overlayBox) Open(name string) (io.ReadCloser, error) {
+	for _, b := range o {
+		ob, ok := b.(OpenBox)
+		if !ok {
+			contents, err := b.Bytes(name)
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			return ioutil.NopCloser(bytes.NewReader(contents)), nil
+		}
+		rc, err := ob.Open(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		return rc, err
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+func (o overlayBox) Stat(name string) (fs.FileInfo, error) {
+	for _, b := range o {
+		sb, ok := b.(StatBox)
+		if !ok {
+			// the first Box with the file must provide its metadata, as a later
+			// one may have a different version of it.
+			_, err := b.Bytes(name)
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			return nil, &fs.PathError{Op: "stat", Path: name, Err: errNoStat}
+		}
+		fi, err := sb.Stat(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		return fi, err
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+// OverlayBox returns a Box which searches the given Boxes in order, using the
+// first one that has the named file. Metadata is unavailable for files found
+// in a Box which does not implement StatBox, and their contents are read
+// instead.
+func OverlayBox(boxes ...Box) OpenBox {
+	return overlayBox(boxes)
+}
+
+// Handler serves and provides URLs for static resources. Each Handler owns
+// its Box and cache, so any number of them may be used in the same process.
 type Handler struct {
-	Path string // Path at which Handler is configured.
-	Box  Box    // Box of files to serve.
+	Path   string        // Path at which Handler is mounted, e.g. "/static/".
+	Box    Box           // Box of files to serve.
+	MaxAge time.Duration // Max age for served files, defaults to 10 years.
+	Logger *slog.Logger  // Optional Logger for load and serve failures.
+
+	// Immutable adds the immutable Cache-Control directive, so browsers do not
+	// revalidate files on reload. Hashed URLs never change content.
+	Immutable bool
+
+	// StaleWhileRevalidate and StaleIfError add the Cache-Control extensions
+	// of the same name when non zero, allowing caches such as CDNs to serve
+	// stale files while revalidating or when the origin fails.
+	StaleWhileRevalidate time.Duration
+	StaleIfError         time.Duration
+
+	// HashFunc is used to fingerprint file contents, and defaults to md5.New.
+	// Changing it changes all generated URLs, such as when using sha256.New.
+	HashFunc func() hash.Hash
+
+	// HashLen is the number of hex characters of the hash used in URLs, and
+	// defaults to 8. A negative value uses the full hash.
+	HashLen int
+
+	// HashMetadata fingerprints files using their name, size and modification
+	// time instead of their contents, which are then only read when served.
+	// It requires the Box to be a StatBox.
+	HashMetadata bool
+
+	// SRI enables Subresource Integrity values for generated URLs, which
+	// components render as integrity and crossorigin attributes.
+	SRI bool
+
+	// BaseURL is the scheme and host, such as "https://cdn.example.com", used
+	// for absolute URLs, which are needed outside the page such as in social
+	// card meta tags.
+	BaseURL string
+
+	// QueryVersion generates URLs for single files as the file name with the
+	// hash in a "v" query parameter, for proxies or CDNs which cannot route
+	// the encoded form. Combined URLs are always encoded, and ServeHTTP
+	// accepts both forms regardless.
+	QueryVersion bool
+
+	// AllowDuplicates keeps repeated names in combined URLs. By default only
+	// the first occurrence of a name is included.
+	AllowDuplicates bool
+
+	// RewriteCSSURLs replaces relative url() references in stylesheets with
+	// the hashed URLs of the referenced files, so they keep working when
+	// combined. Referenced files no larger than CSSInlineSize bytes are
+	// embedded as data URIs instead. Changes to referenced files are picked up
+	// when the stylesheet is reloaded.
+	RewriteCSSURLs bool
+	CSSInlineSize  int
+
+	// FlattenCSSImports inlines the stylesheets referenced by relative
+	// @import rules, so a single request replaces a chain of blocking imports.
+	// Imports with media queries are wrapped in an @media rule.
+	FlattenCSSImports bool
+
+	// CacheSize limits the total bytes of file contents kept in memory, by
+	// evicting the least recently used files. Zero means no limit.
+	CacheSize int64
+
+	// CacheTTL drops files from memory once they have gone unused for the
+	// given duration, so they are reloaded on next use. Zero means no limit.
+	CacheTTL time.Duration
+
+	// CacheShards splits the file cache into the given number of
+	// independently locked parts, to reduce lock contention on busy servers.
+	// CacheSize is divided evenly between them, so eviction is only least
+	// recently used within each part. It must be set before first use, and
+	// defaults to one.
+	CacheShards int
+
+	// CheckModTime stats cached files each time they are used, and reloads
+	// them if their modification time changed. This lets long running
+	// processes pick up redeployed files. It requires the Box to be a StatBox.
+	CheckModTime bool
+
+	// CacheDir, if set, is a directory where combined contents are written
+	// once served, and served from on later requests for the same URL, even
+	// across restarts.
+	CacheDir string
+
+	// NegativeCacheTTL remembers failed requests for the given duration, so
+	// repeated requests for unknown URLs do not load files. Zero disables it.
+	NegativeCacheTTL time.Duration
 
-	mu    sync.RWMutex
-	files map[string]file
+	// GoneStale responds with 410 Gone rather than 404 Not Found for URLs
+	// whose hashes no longer match the files, so clients stop retrying.
+	GoneStale bool
+
+	// StaleFallback serves or redirects to the current files for URLs whose
+	// hashes no longer match, such as those in pages cached by browsers from
+	// before a deploy, rather than failing.
+	StaleFallback Fallback
+
+	// RetiredHashes are prefixes of file hashes which respond with 410 Gone,
+	// for assets intentionally retired by a deploy, so clients and CDNs stop
+	// retrying them.
+	RetiredHashes []string
+
+	// RawPath serves the current version of files at unhashed URLs under it,
+	// such as "raw/" for "/static/raw/css/app.css", for references which
+	// cannot track hashes like those in emails. They are served with
+	// RawMaxAge, and revalidated using their ETag.
+	RawPath   string
+	RawMaxAge time.Duration // Max age for RawPath files, defaults to no-cache.
+
+	// Dev is for local development. Files are read on every use instead of
+	// being cached, single files get unhashed URLs under RawPath, or "dev/"
+	// if it is not set, all files are served in their current version with
+	// no-cache, and errors include the name of the offending file.
+	Dev bool
+
+	// Cache is an optional store shared by several instances. Loaded files
+	// are stored in it, and it is used to serve URLs whose files are missing
+	// or have changed locally, such as those generated by another instance.
+	Cache Cache
+
+	// Gzip serves gzip encoded responses to clients which accept them, for
+	// all but already compressed types such as images. Files are compressed
+	// once as they are loaded and kept in the cache along with their raw
+	// contents, which the cache size includes. Combined URLs and streamed
+	// files are compressed as they are served.
+	Gzip bool
+
+	// Encoders provide additional content codings such as Brotli, in order of
+	// preference and ahead of Gzip. They are used in the same way as Gzip.
+	Encoders []Encoder
+
+	// Precompressed serves files such as app.js.br, app.js.zst and app.js.gz
+	// from the Box, when they exist next to app.js, for clients accepting
+	// those codings. They are used in preference to compressing at runtime,
+	// and regardless of the type of file.
+	Precompressed bool
+
+	// ContentTypes sets the Content-Type served for file extensions, such as
+	// ".glb" for "model/gltf-binary", overriding the built in types for web
+	// assets and those known to the mime package. The type of a combined URL
+	// is that of its first file.
+	ContentTypes map[string]string
+
+	// SourceMaps serves an index source map for combined scripts, at their URL
+	// followed by ".map", and links it with a sourceMappingURL comment. It
+	// uses the existing map for each file, named like "app.js.map", or maps
+	// each line to the original file.
+	SourceMaps bool
+
+	// Compilers compile files by their lower case extension, such as ".ts",
+	// into the scripts or stylesheets served for them. The compiled files are
+	// then treated as files of the output type.
+	Compilers map[string]Compiler
+
+	// Transforms are applied in order to the contents of files by their
+	// lower case extension, such as ".js", before they are hashed or cached.
+	// Compiled files use the extension of their output. Streamed files are
+	// not transformed.
+	Transforms map[string][]Transform
+
+	// Minifier minifies stylesheets and scripts after the Transforms. It is
+	// not used in Dev mode, so files remain readable while debugging.
+	Minifier Minifier
+
+	// ExtHeaders are added to responses by the extension of their first file,
+	// for example to allow cross origin use. When nil, fonts and WebAssembly
+	// are served with "Access-Control-Allow-Origin: *" as browsers require it
+	// for those loaded from another origin such as a CDN. An empty map adds
+	// nothing.
+	ExtHeaders map[string]http.Header
+
+	// CrossOriginResourcePolicy sets the Cross-Origin-Resource-Policy header,
+	// such as "same-site" or "cross-origin", when not empty. Responses always
+	// include "X-Content-Type-Options: nosniff", which HeaderFunc may remove.
+	CrossOriginResourcePolicy string
+
+	// HeaderFunc is called with the name of the first file before a response
+	// is served, to set headers such as Content-Security-Policy or
+	// Timing-Allow-Origin.
+	HeaderFunc func(w http.ResponseWriter, name string)
+
+	// NotFound serves requests for missing assets in place of the plain text
+	// 404 Not Found response, such as to show a branded page or log details.
+	// Responses are not cached.
+	NotFound http.Handler
+
+	// AccessLog is called after each request is served, to feed a logging
+	// pipeline.
+	AccessLog func(Access)
+
+	// StreamSize is the size above which files are streamed from the Box when
+	// served rather than held in the cache, so large assets such as videos do
+	// not take up memory. It requires a Box implementing OpenBox. Zero
+	// disables streaming.
+	StreamSize int64
+
+	// ImageFormats convert PNG and JPEG images into alternative formats, such
+	// as WebP or AVIF, served to clients listing the Content-Type of the
+	// Compiler in their Accept header. The first accepted one is used, and
+	// responses vary on Accept. Converted images are kept in memory,
+	// bounded by CacheSize and CacheTTL.
+	ImageFormats []Compiler
+
+	// ResizeWidths are the widths PNG and JPEG images may be resized to, using
+	// URLs from ResizedURL, such as for the candidates of a srcset. Images are
+	// resized on first request and kept in memory, and are never enlarged.
+	ResizeWidths []int
+
+	mu       sync.RWMutex
+	loads    singleflight.Group
+	memory   shardedCache
+	negative negativeCache
+	hits     atomic.Int64
+	misses   atomic.Int64
+	urls     map[string]ManifestEntry
+
+	manifest map[string]string // loaded by LoadManifest
+	watchers []*fsnotify.Watcher
+	saved    map[string]file // loaded by LoadCache, by name and hash
+	variants fileCache       // converted images, by name, hash and type
+	sizes    sync.Map        // image dimensions, by name
+	digests  fileCache       // integrity values, by entity tag of the files
 }
 
-func (h *Handler) load(name string) (file, error) {
-	// fast path
-	h.mu.RLock()
-	f, found := h.files[name]
-	h.mu.RUnlock()
+// prefix returns Path with a trailing slash, which is what URLs generated by
+// the Handler begin with.
+func (h *Handler) prefix() string {
+	if strings.HasSuffix(h.Path, "/") {
+		return h.Path
+	}
+	return h.Path + "/"
+}
 
-	if found {
-		return f, nil
+func (h *Handler) newHash() hash.Hash {
+	if h.HashFunc == nil {
+		return md5.New()
 	}
+	return h.HashFunc()
+}
 
-	// slow path
-	h.mu.Lock()
-	defer h.mu.Unlock()
+func (h *Handler) hash(contents []byte) string {
+	hs := h.newHash()
+	hs.Write(contents)
+	return h.hashSum(hs)
+}
 
-	// check again in case someone else populated it
-	f, found = h.files[name]
-	if found {
-		return f, nil
+func (h *Handler) hashSum(hs hash.Hash) string {
+	sum := hex.EncodeToString(hs.Sum(nil))
+	n := h.HashLen
+	if n == 0 {
+		n = defaultHashLen
+	}
+	if n < 0 || n > len(sum) {
+		return sum
+	}
+	return sum[:n]
+}
+
+func (h *Handler) debug(msg string, args ...interface{}) {
+	if h.Logger != nil {
+		h.Logger.Debug(msg, args...)
+	}
+}
+
+func (h *Handler) warn(msg string, args ...interface{}) {
+	if h.Logger != nil {
+		h.Logger.Warn(msg, args...)
+	}
+}
+
+func (h *Handler) cacheControl() string {
+	cc := defaultCacheControl
+	if h.MaxAge != 0 {
+		cc = makeCacheControl(h.MaxAge)
+	}
+	if h.Immutable {
+		cc += ", immutable"
+	}
+	if h.StaleWhileRevalidate > 0 {
+		cc += fmt.Sprintf(", stale-while-revalidate=%d", int(h.StaleWhileRevalidate.Seconds()))
+	}
+	if h.StaleIfError > 0 {
+		cc += fmt.Sprintf(", stale-if-error=%d", int(h.StaleIfError.Seconds()))
+	}
+	return cc
+}
+
+func (h *Handler) bytes(name string) ([]byte, error) {
+	contents, _, err := h.bytesDeps(name)
+	return contents, err
+}
+
+// bytesDeps reads and transforms the file, also returning the names of other
+// files the transformed contents depend on.
+func (h *Handler) bytesDeps(name string) ([]byte, []string, error) {
+	contents, err := h.boxBytes(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	var deps []string
+	if contents, err = h.transform(name, contents, &deps); err != nil {
+		return nil, nil, err
 	}
+	return contents, deps, nil
+}
 
+// boxBytes reads the file from the Box, without any transforms.
+func (h *Handler) boxBytes(name string) ([]byte, error) {
+	if h.Box == nil {
+		return nil, ErrNotConfigured
+	}
 	contents, err := h.Box.Bytes(name)
 	if err != nil {
-		return file{}, err
+		h.warn("static: load failed", "name", name, "err", err)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, &ErrAssetNotFound{Name: name, Err: err}
+		}
+		return nil, err
 	}
+	return contents, nil
+}
 
-	hash := fmt.Sprintf("%x", md5.Sum(contents))
-	f = file{
-		Name:    name,
-		Content: contents,
-		Hash:    hash[:hashLen],
+func (h *Handler) stat(sb StatBox, name string) (fs.FileInfo, error) {
+	fi, err := sb.Stat(name)
+	if errors.Is(err, errNoStat) {
+		return nil, err
 	}
-	if h.files == nil {
-		h.files = make(map[string]file)
+	if err != nil {
+		h.warn("static: stat failed", "name", name, "err", err)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, &ErrAssetNotFound{Name: name, Err: err}
+		}
+		return nil, err
+	}
+	return fi, nil
+}
+
+// read fetches and fingerprints the named file. With HashMetadata the
+// contents are not read, and are instead fetched when they are served.
+func (h *Handler) read(name string) (file, error) {
+	f := file{Name: name, ContentType: h.typeByExtension(path.Ext(name))}
+	ob, streamable := h.Box.(OpenBox)
+	streamable = streamable && h.StreamSize > 0
+	if sb, ok := h.Box.(StatBox); ok && (h.HashMetadata || h.CheckModTime || streamable) {
+		fi, err := h.stat(sb, name)
+		if errors.Is(err, errNoStat) {
+			return h.readContents(f)
+		}
+		if err != nil {
+			return file{}, err
+		}
+		f.ModTime = fi.ModTime()
+		if h.HashMetadata {
+			meta := fmt.Sprintf("%s:%d:%d", name, fi.Size(), fi.ModTime().UnixNano())
+			f.Hash = h.hash([]byte(meta))
+			return f, nil
+		}
+		if streamable && fi.Size() > h.StreamSize {
+			return h.readStream(ob, f, fi.Size())
+		}
 	}
-	h.files[name] = f
+	return h.readContents(f)
+}
 
+// readContents reads and fingerprints the contents of the file.
+func (h *Handler) readContents(f file) (file, error) {
+	contents, deps, err := h.bytesDeps(f.Name)
+	if err != nil {
+		return file{}, err
+	}
+	if sb, ok := h.Box.(StatBox); ok && h.CheckModTime && len(deps) > 0 {
+		f.Deps = make(map[string]time.Time, len(deps))
+		for _, dep := range deps {
+			f.Deps[dep] = modTime(sb, dep)
+		}
+	}
+	f.Content = contents
+	f.Hash = h.hash(contents)
+	if f.Encoded, err = h.encode(f.Name, contents); err != nil {
+		return file{}, err
+	}
 	return f, nil
 }
 
+// fresh reports if a cached file is still current. Only files whose
+// modification time is being checked can go stale, including when a file
+// their contents depend on changes.
+func (h *Handler) fresh(f file) bool {
+	sb, ok := h.Box.(StatBox)
+	if !ok || !h.CheckModTime {
+		return true
+	}
+	fi, err := sb.Stat(f.Name)
+	if errors.Is(err, errNoStat) {
+		if !f.ModTime.IsZero() {
+			return false
+		}
+	} else if err != nil || !fi.ModTime().Equal(f.ModTime) {
+		return false
+	}
+	for dep, mtime := range f.Deps {
+		if !modTime(sb, dep).Equal(mtime) {
+			return false
+		}
+	}
+	return true
+}
+
+// modTime returns the modification time of the file, or the zero time if it
+// cannot be found.
+func modTime(sb StatBox, name string) time.Time {
+	fi, err := sb.Stat(name)
+	if err != nil {
+		return time.Time{}
+	}
+	return fi.ModTime()
+}
+
+// cleanName normalizes names so the same files produce the same URLs
+// regardless of the platform they were generated on.
+func cleanName(name string) string {
+	return path.Clean(strings.ReplaceAll(name, `\`, "/"))
+}
+
+// bundleNames cleans the names, and drops repeated names keeping the first
+// occurrence unless AllowDuplicates is set.
+func (h *Handler) bundleNames(names []string) ([]string, error) {
+	cleaned := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if err := validName(name); err != nil {
+			return nil, err
+		}
+		name = cleanName(name)
+		if seen[name] && !h.AllowDuplicates {
+			continue
+		}
+		seen[name] = true
+		cleaned = append(cleaned, name)
+	}
+	return cleaned, nil
+}
+
+// validName returns an ErrInvalidName if the name contains a null byte or
+// refers to a parent directory, so it cannot escape the root of the Box.
+func validName(name string) error {
+	if strings.IndexByte(name, 0) >= 0 {
+		return &ErrInvalidName{Name: name, Reason: "contains a null byte"}
+	}
+	for _, part := range strings.Split(strings.ReplaceAll(name, `\`, "/"), "/") {
+		if part == ".." {
+			return &ErrInvalidName{Name: name, Reason: "refers to a parent directory"}
+		}
+	}
+	return nil
+}
+
+func (h *Handler) load(name string) (file, error) {
+	name = cleanName(name)
+	if h.Dev {
+		return h.loadDev(name)
+	}
+
+	// fast path
+	if f, found := h.cache().get(name, h.CacheTTL); found && h.fresh(f) {
+		h.hits.Add(1)
+		return f, nil
+	}
+	h.misses.Add(1)
+
+	// slow path, concurrent loads of the same file share a single read
+	v, err, _ := h.loads.Do(name, func() (interface{}, error) {
+		// check again in case someone else populated it
+		if f, found := h.cache().get(name, h.CacheTTL); found && h.fresh(f) {
+			return f, nil
+		}
+
+		f, err := h.read(name)
+		if err != nil {
+			return file{}, err
+		}
+		h.storeShared(f)
+		for _, evicted := range h.cache().add(f, h.CacheSize, h.CacheTTL) {
+			h.debug("static: evicted from cache", "name", evicted.Name)
+		}
+		return f, nil
+	})
+	if err != nil {
+		return file{}, err
+	}
+	return v.(file), nil
+}
+
 // URL returns a hashed URL for all the given component names. It uses the
 // extension of the first file as the extension for the generated URL.
 func (h *Handler) URL(names ...string) (string, error) {
+	return h.URLContext(context.Background(), names...)
+}
+
+// URLContext is like URL, but stops loading files and returns the context
+// error once ctx is done.
+func (h *Handler) URLContext(ctx context.Context, names ...string) (string, error) {
 	if len(names) == 0 {
 		return "", errZeroNames
 	}
 
+	names, err := h.bundleNames(names)
+	if err != nil {
+		return "", err
+	}
+	if h.Dev {
+		return h.devURL(names)
+	}
+	if u, found := h.lookupManifest(names); found {
+		return u, nil
+	}
+
 	files := make([]file, 0, len(names))
 	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
 		f, err := h.load(name)
 		if err != nil {
 			return "", err
@@ -212,197 +899,486 @@ func (h *Handler) URL(names ...string) (string, error) {
 		files = append(files, f)
 	}
 
+	u, err := h.makeURL(files)
+	if err != nil {
+		return "", err
+	}
+	h.remember(files, u)
+	return u, nil
+}
+
+// URLs returns individual hashed URLs for each of the given names, such as to
+// reference the members of a combined URL separately while debugging.
+func (h *Handler) URLs(names ...string) ([]string, error) {
+	return h.URLsContext(context.Background(), names...)
+}
+
+// URLsContext is like URLs, but stops loading files and returns the context
+// error once ctx is done.
+func (h *Handler) URLsContext(ctx context.Context, names ...string) ([]string, error) {
+	if len(names) == 0 {
+		return nil, errZeroNames
+	}
+	names, err := h.bundleNames(names)
+	if err != nil {
+		return nil, err
+	}
+	urls := make([]string, 0, len(names))
+	for _, name := range names {
+		u, err := h.URLContext(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		urls = append(urls, u)
+	}
+	return urls, nil
+}
+
+// Preload generates the URLs for each of the given bundles, filling the cache
+// so the first requests do not pay for loading and hashing files. It returns
+// the first error, so missing files can fail fast at startup.
+func (h *Handler) Preload(bundles ...[]string) error {
+	for _, names := range bundles {
+		if _, err := h.URL(names...); err != nil {
+			return fmt.Errorf("static: preload %v: %w", names, err)
+		}
+	}
+	return nil
+}
+
+func (h *Handler) makeURL(files []file) (string, error) {
+	if h.QueryVersion && len(files) == 1 {
+		return path.Join(h.Path, files[0].Name) + "?v=" + files[0].Hash, nil
+	}
+
 	value, err := encode(files)
 	if err != nil {
 		return "", err
 	}
 
-	if ext := filepath.Ext(names[0]); ext != "" {
+	if ext := path.Ext(files[0].Name); ext != "" {
 		value = value + ext
 	}
 
 	return path.Join(h.Path, value), nil
 }
 
-// ServeHTTP handles requests for hashed URLs.
+// resolve fills in the contents of decoded files, ensuring their hashes match
+// the current contents.
+func (h *Handler) resolve(files []file) error {
+	for i, f := range files {
+		if saved, found := h.lookupSaved(f); found {
+			files[i] = saved
+			continue
+		}
+		loaded, err := h.load(f.Name)
+		if err == nil && loaded.Hash != f.Hash {
+			err = &ErrStaleHash{Name: f.Name, Hash: f.Hash}
+		}
+		if err != nil {
+			if shared, found := h.lookupShared(f); found {
+				files[i] = shared
+				continue
+			}
+			return err
+		}
+		if loaded.Content == nil && loaded.Streamed == 0 {
+			if loaded.Content, err = h.bytes(f.Name); err != nil {
+				return err
+			}
+		}
+		files[i] = loaded
+	}
+	return nil
+}
+
+var _ io.Closer = (*Handler)(nil)
+
+// Close stops any background work and releases cached files. The Handler
+// should not be used after it is closed.
+func (h *Handler) Close() error {
+	h.mu.Lock()
+	watchers := h.watchers
+	h.watchers = nil
+	h.urls = nil
+	h.manifest = nil
+	h.saved = nil
+	h.mu.Unlock()
+
+	var err error
+	for _, w := range watchers {
+		if werr := w.Close(); werr != nil && err == nil {
+			err = werr
+		}
+	}
+	h.cache().clear()
+	h.negative.clear()
+	return err
+}
+
+// parseRequest returns the files and content type for the path following the
+// Handler prefix, which is either encoded or a name with a version query.
+func (h *Handler) parseRequest(r *http.Request, rest string) ([]file, string, error) {
+	ext := path.Ext(rest)
+	contentType := h.typeByExtension(ext)
+
+	if v := r.URL.Query().Get("v"); v != "" {
+		if rest == "" {
+			return nil, "", errInvalidURL(r.URL.String())
+		}
+		return []file{{Name: rest, Hash: v}}, contentType, nil
+	}
+
+	files, err := decode(rest[:len(rest)-len(ext)])
+	if err != nil {
+		return nil, "", err
+	}
+	return files, contentType, nil
+}
+
+var _ http.Handler = (*Handler)(nil)
+
+// ServeHTTP handles requests for hashed URLs. The Handler can be mounted on a
+// http.ServeMux, or any other router, at its Path.
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	path := r.URL.Path
-	if !strings.HasPrefix(path, h.Path) {
-		notFound(w)
+	if h.AccessLog != nil {
+		h.serveLogged(w, r, h.serve)
 		return
 	}
+	h.serve(w, r)
+}
 
-	contentType := ""
-	encoded := path[len(h.Path):]
-	if ext := filepath.Ext(encoded); ext != "" {
-		encoded = encoded[:len(encoded)-len(ext)]
-		contentType = mime.TypeByExtension(ext)
+func (h *Handler) serve(w http.ResponseWriter, r *http.Request) {
+	urlPath := r.URL.Path
+	prefix := h.prefix()
+	if !strings.HasPrefix(urlPath, prefix) {
+		h.writeFailure(w, r, http.StatusNotFound)
+		return
 	}
 
-	files, err := decode(encoded)
+	rest := urlPath[len(prefix):]
+	width, rest, err := h.resizeWidth(rest)
 	if err != nil {
+		h.warn("static: bad request", "path", urlPath, "err", err)
 		badRequest(w)
 		return
 	}
 
-	// fill in the contents and calculate the length
-	var contentLength int
-	for i, f := range files {
-		loaded, err := h.load(f.Name)
+	rawName, raw := h.rawName(rest)
+	var files []file
+	var contentType string
+	if raw {
+		files = []file{{Name: rawName}}
+		contentType = h.typeByExtension(path.Ext(rawName))
+	} else if mapped, ok := h.sourceMapRequest(rest); ok {
+		h.serveSourceMap(w, r, mapped)
+		return
+	} else {
+		files, contentType, err = h.parseRequest(r, rest)
 		if err != nil {
-			notFound(w)
+			h.warn("static: bad request", "path", urlPath, "err", err)
+			badRequest(w)
+			return
+		}
+	}
+
+	h.serveFiles(w, r, files, contentType, raw || h.Dev, width)
+}
+
+// serveFiles serves the decoded files. Raw files are served in their current
+// version, and images are resized to width if it is not zero.
+func (h *Handler) serveFiles(w http.ResponseWriter, r *http.Request, files []file, contentType string, raw bool, width int) {
+	urlPath := r.URL.Path
+	for _, f := range files {
+		if err := validName(f.Name); err != nil {
+			h.warn("static: bad request", "path", urlPath, "err", err)
+			badRequest(w)
 			return
 		}
-		if loaded.Hash != f.Hash {
-			notFound(w)
+	}
+
+	if h.Box == nil {
+		h.warn("static: serve failed", "path", urlPath, "err", ErrNotConfigured)
+		serviceUnavailable(w)
+		return
+	}
+
+	if raw {
+		for i := range files {
+			loaded, err := h.load(files[i].Name)
+			if err != nil {
+				h.warn("static: not found", "path", urlPath, "err", err)
+				h.writeFailure(w, r, http.StatusNotFound)
+				return
+			}
+			files[i].Hash = loaded.Hash
+		}
+		w.Header().Set("Cache-Control", h.rawCacheControl())
+	}
+
+	h.securityHeaders(w)
+	h.extHeaders(w, path.Ext(files[0].Name))
+	if h.HeaderFunc != nil {
+		h.HeaderFunc(w, files[0].Name)
+	}
+	if h.retired(files) {
+		h.writeFailure(w, r, http.StatusGone)
+		return
+	}
+
+	// the entity tag of a converted image is only known once the conversion
+	// succeeds, so it is checked after the files are resolved.
+	variant, negotiated := h.imageVariant(r, files, width)
+	if negotiated {
+		w.Header().Add("Vary", "Accept")
+	}
+
+	var codings []string
+	if all := h.codings(); len(all) > 0 {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if h.Precompressed || compressible(contentType) {
+			codings = accepted(all, r.Header.Get("Accept-Encoding"))
+		}
+	}
+
+	if variant == nil && h.notModified(w, r, etag(files), codings) {
+		return
+	}
+
+	if h.CacheDir != "" && len(codings) == 0 && variant == nil && h.serveDisk(w, files, contentType) {
+		return
+	}
+
+	negativeKey, _ := encode(files)
+	if status, found := h.negative.get(negativeKey); found {
+		h.writeFailure(w, r, status)
+		return
+	}
+
+	err := h.resolve(files)
+	var stale *ErrStaleHash
+	if err != nil && h.StaleFallback != FallbackNone && errors.As(err, &stale) {
+		if current, cerr := h.current(files); cerr == nil {
+			if h.StaleFallback == FallbackRedirect {
+				h.redirectCurrent(w, r, current)
+				return
+			}
+			files, err = current, nil
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("ETag", etag(files))
+		}
+	}
+	if err != nil {
+		h.warn("static: not found", "path", urlPath, "err", err)
+		status := h.failureStatus(err)
+		if h.NegativeCacheTTL > 0 {
+			h.negative.add(negativeKey, status, h.NegativeCacheTTL)
+		}
+		h.writeFailure(w, r, status)
+		return
+	}
+
+	if h.CacheDir != "" && !streamed(files) {
+		if err := h.writeDisk(files); err != nil {
+			h.warn("static: disk cache write failed", "path", urlPath, "err", err)
+		}
+	}
+
+	if variant != nil {
+		if h.serveVariant(w, r, files, variant) {
+			return
+		}
+		if h.notModified(w, r, etag(files), codings) {
 			return
 		}
-		contentLength += len(loaded.Content)
-		files[i] = loaded
 	}
 
-	header := w.Header()
-	header.Set("Cache-Control", cacheControl)
-	header.Set("Content-Length", strconv.Itoa(contentLength))
-	if contentType != "" {
-		header.Set("Content-Type", contentType)
+	if files[0].ContentType != "" {
+		contentType = files[0].ContentType
+	}
+	if len(codings) > 0 && h.serveEncoded(w, files, contentType, codings) {
+		return
+	}
+	if len(files) == 1 && files[0].Streamed > 0 && h.sourceMapTrailer(files) == nil &&
+		h.serveStream(w, r, files[0], contentType) {
+		return
 	}
 
+	contentLength := len(h.separator(files[0].Name))*(len(files)-1) + len(h.sourceMapTrailer(files))
 	for _, f := range files {
-		w.Write(f.Content)
+		contentLength += len(f.Content) + int(f.Streamed)
+	}
+	h.writeHeaders(w, int64(contentLength), contentType)
+	if err := h.writeFiles(w, files); err != nil {
+		h.warn("static: stream failed", "path", urlPath, "err", err)
 	}
 }
 
-// LinkStyle provides a h.LinkStyle where the HREFs are combined and served
-// using the specified Handler.
-type LinkStyle struct {
-	HREF []string
+// writeFiles writes the combined contents of the resolved files.
+func (h *Handler) writeFiles(w io.Writer, files []file) error {
+	sep := h.separator(files[0].Name)
+	for i, f := range files {
+		if i > 0 && len(sep) > 0 {
+			if _, err := w.Write(sep); err != nil {
+				return err
+			}
+		}
+		if f.Streamed > 0 {
+			if err := h.stream(w, f); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := w.Write(f.Content); err != nil {
+			return err
+		}
+	}
+	if trailer := h.sourceMapTrailer(files); trailer != nil {
+		if _, err := w.Write(trailer); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-// HTML returns the <link> tag with the appropriate attributes.
-func (l *LinkStyle) HTML(ctx context.Context) (h.HTML, error) {
-	url, err := URL(ctx, l.HREF...)
-	if err != nil {
-		return nil, err
+// separator returns the bytes inserted between combined files of the type of
+// name, so a file ending without a newline or semicolon, such as in a line
+// comment, does not break the next one.
+func (h *Handler) separator(name string) []byte {
+	switch h.outputExt(name) {
+	case ".js", ".mjs":
+		return []byte("\n;\n")
+	case ".css":
+		return []byte("\n")
 	}
-	return &h.LinkStyle{HREF: url}, nil
+	return nil
 }
 
-// Script provides a h.Script where the Srcs are combined and served using the
-// specified Handler.
-type Script struct {
-	Src   []string
-	Async bool
+// retired reports if any of the files has one of the RetiredHashes.
+func (h *Handler) retired(files []file) bool {
+	for _, f := range files {
+		for _, prefix := range h.RetiredHashes {
+			if prefix != "" && strings.HasPrefix(f.Hash, prefix) {
+				return true
+			}
+		}
+	}
+	return false
 }
 
-// HTML returns the <script> tag with the appropriate attributes.
-func (l *Script) HTML(ctx context.Context) (h.HTML, error) {
-	url, err := URL(ctx, l.Src...)
-	if err != nil {
-		return nil, err
+func (h *Handler) failureStatus(err error) int {
+	var stale *ErrStaleHash
+	if h.GoneStale && errors.As(err, &stale) {
+		return http.StatusGone
 	}
-	return &h.Script{
-		Src:   url,
-		Async: l.Async,
-	}, nil
+	return http.StatusNotFound
 }
 
-// Img provides a h.Img where the src is served using the specified Handler.
-type Img struct {
-	ID    string
-	Class string
-	Style string
-	Src   string
-	Alt   string
+// notModified sets the entity tag, and responds with 304 Not Modified if the
+// request already has it, or has the tag of the contents in one of the
+// accepted codings.
+func (h *Handler) notModified(w http.ResponseWriter, r *http.Request, tag string, codings []string) bool {
+	w.Header().Set("ETag", tag)
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	tags := []string{tag}
+	for _, coding := range codings {
+		tags = append(tags, codedETag(tag, coding))
+	}
+	for _, t := range tags {
+		if etagMatch(header, t) {
+			w.Header().Set("ETag", t)
+			h.setCacheControl(w.Header())
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
 }
 
-// HTML returns the <img> tag with the appropriate attributes.
-func (i *Img) HTML(ctx context.Context) (h.HTML, error) {
-	src, err := URL(ctx, i.Src)
-	if err != nil {
-		return nil, err
+// setCacheControl sets the Cache-Control header, unless it was already set
+// for the response.
+func (h *Handler) setCacheControl(header http.Header) {
+	if header.Get("Cache-Control") == "" {
+		header.Set("Cache-Control", h.cacheControl())
 	}
-	return &h.Img{
-		ID:    i.ID,
-		Class: i.Class,
-		Style: i.Style,
-		Src:   src,
-		Alt:   i.Alt,
-	}, nil
 }
 
-// Favicon provides a h.Link for a favicon.
-type Favicon struct {
-	HREF string
+func (h *Handler) writeHeaders(w http.ResponseWriter, contentLength int64, contentType string) {
+	header := w.Header()
+	h.setCacheControl(header)
+	header.Set("Content-Length", strconv.FormatInt(contentLength, 10))
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
 }
 
-// HTML returns the <script> tag with the appropriate attributes.
-func (l *Favicon) HTML(ctx context.Context) (h.HTML, error) {
-	url, err := URL(ctx, l.HREF)
-	if err != nil {
-		return nil, err
-	}
-	return &h.Link{
-		Rel:  "shortcut icon",
-		HREF: url,
-	}, nil
+// Middleware returns a http.Handler which serves requests under Path using the
+// Handler, and passes all other requests to next.
+func (h *Handler) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, h.prefix()) {
+			h.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
 }
 
-// Input renders a HTML <input> tag with the Src URL transformed.
-type Input struct {
-	ID          string
-	Class       string
-	Name        string
-	Style       string
-	Type        string
-	Value       string
-	Src         string
-	Placeholder string
-	Checked     bool
-	Multiple    bool
-	Data        map[string]interface{}
-	Inner       h.HTML
-}
-
-// HTML renders the content.
-func (i *Input) HTML(ctx context.Context) (h.HTML, error) {
-	src, err := URL(ctx, i.Src)
-	if err != nil {
-		return nil, err
-	}
-	return &h.Input{
-		ID:          i.ID,
-		Class:       i.Class,
-		Name:        i.Name,
-		Style:       i.Style,
-		Type:        i.Type,
-		Value:       i.Value,
-		Src:         src,
-		Placeholder: i.Placeholder,
-		Checked:     i.Checked,
-		Multiple:    i.Multiple,
-		Data:        i.Data,
-		Inner:       i.Inner,
-	}, nil
+// Resolver provides hashed URLs for named files. Handler is the standard
+// implementation, and components should depend only on this interface.
+type Resolver interface {
+	URLContext(ctx context.Context, names ...string) (string, error)
 }
 
+var _ Resolver = (*Handler)(nil)
+
 type ctxKey int
 
-const handlerCtxKey ctxKey = 0
+const resolverCtxKey ctxKey = 0
 
-func NewContext(ctx context.Context, h *Handler) context.Context {
-	return context.WithValue(ctx, handlerCtxKey, h)
+// NewContext returns a new context carrying the given Resolver.
+func NewContext(ctx context.Context, r Resolver) context.Context {
+	return context.WithValue(ctx, resolverCtxKey, r)
 }
 
-func FromContext(ctx context.Context) *Handler {
-	h, _ := ctx.Value(handlerCtxKey).(*Handler)
-	return h
+// FromContext returns the Resolver in the context, or nil if there is none.
+func FromContext(ctx context.Context) Resolver {
+	r, _ := ctx.Value(resolverCtxKey).(Resolver)
+	return r
 }
 
+// URL returns a hashed URL for the given names using the Resolver in the
+// context.
 func URL(ctx context.Context, names ...string) (string, error) {
-	h := FromContext(ctx)
-	if h == nil {
+	r := FromContext(ctx)
+	if r == nil {
 		return "", errNoHandlerInContext
 	}
-	return h.URL(names...)
+	return r.URLContext(ctx, names...)
+}
+
+// URLs returns individual hashed URLs for each of the given names using the
+// Resolver in the context.
+func URLs(ctx context.Context, names ...string) ([]string, error) {
+	r := FromContext(ctx)
+	if r == nil {
+		return nil, errNoHandlerInContext
+	}
+	if ur, ok := r.(interface {
+		URLsContext(ctx context.Context, names ...string) ([]string, error)
+	}); ok {
+		return ur.URLsContext(ctx, names...)
+	}
+	urls := make([]string, 0, len(names))
+	for _, name := range names {
+		u, err := r.URLContext(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		urls = append(urls, u)
+	}
+	return urls, nil
 }
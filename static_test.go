@@ -1,17 +1,28 @@
 package static
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
 	"encoding/base64"
 	"errors"
+	"io"
+	"io/fs"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
 	"regexp"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"golang.org/x/net/context"
 
-	"github.com/daaku/go.h"
 	"github.com/facebookgo/ensure"
 )
 
@@ -37,6 +48,30 @@ func TestNotFound(t *testing.T) {
 	ensure.DeepEqual(t, w.Code, http.StatusNotFound)
 }
 
+func TestNotFoundHandler(t *testing.T) {
+	var paths []string
+	h := &Handler{
+		Path: "/static/",
+		Box: funcBox(func(name string) ([]byte, error) {
+			return nil, os.ErrNotExist
+		}),
+		NotFound: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			paths = append(paths, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, "branded")
+		}),
+	}
+	for _, p := range []string{"/static/W1siZm9vIiwiYmFyIl1d", "/other"} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", p, nil))
+		ensure.DeepEqual(t, w.Code, http.StatusNotFound)
+		ensure.DeepEqual(t, w.Body.String(), "branded")
+		ensure.DeepEqual(t, w.Header().Get("ETag"), "")
+		ensureDisableCaching(t, w.Header())
+	}
+	ensure.DeepEqual(t, paths, []string{"/static/W1siZm9vIiwiYmFyIl1d", "/other"})
+}
+
 func TestBadRequest(t *testing.T) {
 	w := httptest.NewRecorder()
 	badRequest(w)
@@ -44,6 +79,13 @@ func TestBadRequest(t *testing.T) {
 	ensure.DeepEqual(t, w.Code, http.StatusBadRequest)
 }
 
+func TestServiceUnavailable(t *testing.T) {
+	w := httptest.NewRecorder()
+	serviceUnavailable(w)
+	ensureDisableCaching(t, w.Header())
+	ensure.DeepEqual(t, w.Code, http.StatusServiceUnavailable)
+}
+
 func TestErrInvalidURL(t *testing.T) {
 	ensure.DeepEqual(t, errInvalidURL("foo").Error(), `static: invalid URL "foo"`)
 }
@@ -136,6 +178,240 @@ func TestLoadFromBox(t *testing.T) {
 	})
 }
 
+func TestFSBox(t *testing.T) {
+	b := FSBox(fstest.MapFS{
+		"foo/bar.css": &fstest.MapFile{Data: []byte("baz")},
+	})
+	for _, name := range []string{"foo/bar.css", "/foo/bar.css"} {
+		v, err := b.Bytes(name)
+		ensure.Nil(t, err, name)
+		ensure.DeepEqual(t, string(v), "baz", name)
+	}
+	_, err := b.Bytes("missing.css")
+	ensure.True(t, os.IsNotExist(err), err)
+}
+
+//go:embed testdata
+var testdata embed.FS
+
+func TestEmbedBox(t *testing.T) {
+	b, err := EmbedBox(testdata, "testdata")
+	ensure.Nil(t, err)
+	v, err := b.Bytes("foo.css")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, string(v), "body{}\n")
+}
+
+func TestEmbedBoxInvalidRoot(t *testing.T) {
+	_, err := EmbedBox(testdata, "../foo")
+	ensure.Err(t, err, regexp.MustCompile("invalid"))
+}
+
+func TestStatBox(t *testing.T) {
+	fsys := fstest.MapFS{
+		"foo.css": &fstest.MapFile{Data: []byte("baz"), ModTime: time.Unix(42, 0)},
+	}
+	for _, b := range []StatBox{FSBox(fsys), FileSystemBox(http.FS(fsys))} {
+		fi, err := b.Stat("/foo.css")
+		ensure.Nil(t, err)
+		ensure.DeepEqual(t, fi.Size(), int64(3))
+		ensure.True(t, fi.ModTime().Equal(time.Unix(42, 0)))
+		_, err = b.Stat("/missing.css")
+		ensure.True(t, os.IsNotExist(err), err)
+	}
+}
+
+func TestOverlayBox(t *testing.T) {
+	b := OverlayBox(
+		FSBox(fstest.MapFS{
+			"theme.css": &fstest.MapFile{Data: []byte("override")},
+		}),
+		funcBox(func(name string) ([]byte, error) {
+			if name == "broken.css" {
+				return nil, errors.New("broken")
+			}
+			return nil, os.ErrNotExist
+		}),
+		FSBox(fstest.MapFS{
+			"theme.css": &fstest.MapFile{Data: []byte("base")},
+			"app.css":   &fstest.MapFile{Data: []byte("app")},
+		}),
+	)
+	v, err := b.Bytes("theme.css")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, string(v), "override")
+	v, err = b.Bytes("app.css")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, string(v), "app")
+	fi, err := b.Stat("app.css")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, fi.Size(), int64(3))
+	_, err = b.Bytes("broken.css")
+	ensure.Err(t, err, regexp.MustCompile("broken"))
+	_, err = b.Bytes("missing.css")
+	ensure.True(t, os.IsNotExist(err), err)
+	_, err = b.Stat("missing.css")
+	ensure.True(t, os.IsNotExist(err), err)
+}
+
+func TestOverlayBoxStatShadowed(t *testing.T) {
+	b := OverlayBox(
+		funcBox(func(name string) ([]byte, error) {
+			if name == "theme.css" {
+				return []byte("foo"), nil
+			}
+			return nil, os.ErrNotExist
+		}),
+		FSBox(fstest.MapFS{
+			"theme.css": &fstest.MapFile{Data: []byte("base"), ModTime: time.Unix(42, 0)},
+		}),
+	)
+	_, err := b.(StatBox).Stat("theme.css")
+	ensure.True(t, errors.Is(err, errNoStat), err)
+
+	h := &Handler{Box: b, HashMetadata: true, CheckModTime: true}
+	f, err := h.load("theme.css")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, f.Hash, "acbd18db")
+	ensure.DeepEqual(t, string(f.Content), "foo")
+	ensure.True(t, h.fresh(f))
+}
+
+func TestLoadSHA256(t *testing.T) {
+	h := Handler{
+		HashFunc: sha256.New,
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	}
+	f, err := h.load("foo")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, f.Hash, "2c26b46b")
+}
+
+func TestLoadHashLen(t *testing.T) {
+	cases := []struct {
+		Len  int
+		Hash string
+	}{
+		{Len: 0, Hash: "acbd18db"},
+		{Len: 4, Hash: "acbd"},
+		{Len: 12, Hash: "acbd18db4cc2"},
+		{Len: -1, Hash: "acbd18db4cc2f85cedef654fccc4a4d8"},
+		{Len: 100, Hash: "acbd18db4cc2f85cedef654fccc4a4d8"},
+	}
+	for _, c := range cases {
+		h := Handler{
+			HashLen: c.Len,
+			Box: funcBox(func(name string) ([]byte, error) {
+				return []byte("foo"), nil
+			}),
+		}
+		f, err := h.load("foo")
+		ensure.Nil(t, err)
+		ensure.DeepEqual(t, f.Hash, c.Hash, c)
+	}
+}
+
+func TestLoadHashMetadata(t *testing.T) {
+	fsys := fstest.MapFS{
+		"foo.css": &fstest.MapFile{Data: []byte("foo"), ModTime: time.Unix(42, 0)},
+	}
+	h := Handler{
+		Path:         "/",
+		Box:          FSBox(fsys),
+		HashMetadata: true,
+	}
+	f, err := h.load("foo.css")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, f, file{
+		Name:        "foo.css",
+		ContentType: "text/css; charset=utf-8",
+		Hash:        "f5e0d243",
+		ModTime:     time.Unix(42, 0),
+	})
+
+	v, err := h.URL("foo.css")
+	ensure.Nil(t, err)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, &http.Request{URL: &url.URL{Path: v}})
+	ensure.DeepEqual(t, w.Code, http.StatusOK)
+	ensure.DeepEqual(t, w.Body.String(), "foo")
+}
+
+func TestLoadHashMetadataStatError(t *testing.T) {
+	h := Handler{Box: FSBox(fstest.MapFS{}), HashMetadata: true}
+	_, err := h.load("foo.css")
+	var notFound *ErrAssetNotFound
+	ensure.True(t, errors.As(err, &notFound), err)
+}
+
+func TestCleanName(t *testing.T) {
+	cases := []struct{ In, Out string }{
+		{In: "foo.css", Out: "foo.css"},
+		{In: "/foo.css", Out: "/foo.css"},
+		{In: "css/foo.css", Out: "css/foo.css"},
+		{In: `css\foo.css`, Out: "css/foo.css"},
+		{In: "./css//foo.css", Out: "css/foo.css"},
+	}
+	for _, c := range cases {
+		ensure.DeepEqual(t, cleanName(c.In), c.Out, c)
+	}
+}
+
+func TestInvalidNames(t *testing.T) {
+	h := Handler{
+		Path: "/",
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	}
+	for _, name := range []string{"../foo.css", "css/../../foo.css", `css\..\..\foo.css`, "/..", "foo\x00.css"} {
+		_, err := h.URL("a.css", name)
+		var invalid *ErrInvalidName
+		ensure.True(t, errors.As(err, &invalid), name)
+		ensure.DeepEqual(t, invalid.Name, name)
+	}
+	_, err := h.URL("css/./foo.css", "/foo.css")
+	ensure.Nil(t, err)
+
+	// [["../foo","bar"]]
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/W1siLi4vZm9vIiwiYmFyIl1d", nil))
+	ensure.DeepEqual(t, w.Code, http.StatusBadRequest)
+}
+
+func TestCombinedURLWindowsSeparators(t *testing.T) {
+	h := Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			ensure.DeepEqual(t, name, "css/foo.css")
+			return []byte("foo"), nil
+		}),
+	}
+	v1, err := h.URL(`css\foo.css`)
+	ensure.Nil(t, err)
+	v2, err := h.URL("css/foo.css")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v1, v2)
+}
+
+func TestLoadCheckModTime(t *testing.T) {
+	fsys := fstest.MapFS{
+		"foo.css": &fstest.MapFile{Data: []byte("foo"), ModTime: time.Unix(1, 0)},
+	}
+	h := Handler{Box: FSBox(fsys), CheckModTime: true}
+	v1, err := h.URL("foo.css")
+	ensure.Nil(t, err)
+	v2, err := h.URL("foo.css")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v1, v2)
+
+	fsys["foo.css"] = &fstest.MapFile{Data: []byte("bar"), ModTime: time.Unix(2, 0)}
+	v3, err := h.URL("foo.css")
+	ensure.Nil(t, err)
+	ensure.NotDeepEqual(t, v3, v1)
+}
+
 func TestLoadFromCache(t *testing.T) {
 	const magic = "foo"
 	h := Handler{
@@ -168,6 +444,35 @@ func TestLoadFromBoxError(t *testing.T) {
 	ensure.Err(t, err, regexp.MustCompile(msg))
 }
 
+func TestLoadNotConfigured(t *testing.T) {
+	var h Handler
+	_, err := h.load("foo")
+	ensure.True(t, err == ErrNotConfigured, err)
+}
+
+func TestLoadAssetNotFound(t *testing.T) {
+	h := Handler{Box: FSBox(fstest.MapFS{})}
+	_, err := h.load("foo.css")
+	var notFound *ErrAssetNotFound
+	ensure.True(t, errors.As(err, &notFound), err)
+	ensure.DeepEqual(t, notFound.Name, "foo.css")
+	ensure.True(t, errors.Is(err, fs.ErrNotExist), err)
+	ensure.Err(t, err, regexp.MustCompile(`static: asset "foo.css" not found`))
+}
+
+func TestResolveStaleHash(t *testing.T) {
+	h := Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	}
+	err := h.resolve([]file{{Name: "foo", Hash: "bar"}})
+	var stale *ErrStaleHash
+	ensure.True(t, errors.As(err, &stale), err)
+	ensure.DeepEqual(t, stale, &ErrStaleHash{Name: "foo", Hash: "bar"})
+	ensure.Err(t, err, regexp.MustCompile(`static: stale hash "bar" for asset "foo"`))
+}
+
 func TestCombinedURLNoNames(t *testing.T) {
 	var h Handler
 	v, err := h.URL()
@@ -204,6 +509,27 @@ func TestCombinedURLMultiple(t *testing.T) {
 	ensure.DeepEqual(t, v, "W1sibjEiLCJhY2JkMThkYiJdLFsibjIiLCIzN2I1MWQxOSJdXQ")
 }
 
+func TestCombinedURLDuplicates(t *testing.T) {
+	h := Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte(name), nil
+		}),
+	}
+	v, err := h.URL("a", "b", "a")
+	ensure.Nil(t, err)
+	v2, err := h.URL("a", "b")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, v2)
+
+	h.AllowDuplicates = true
+	v3, err := h.URL("a", "b", "a")
+	ensure.Nil(t, err)
+	ensure.NotDeepEqual(t, v3, v2)
+	files, err := decode(v3)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, len(files), 3)
+}
+
 func TestCombinedURLExt(t *testing.T) {
 	contents := [][]byte{
 		[]byte("foo"),
@@ -245,11 +571,13 @@ func TestServeCombinedURLWithExt(t *testing.T) {
 	}
 	h.ServeHTTP(w, r)
 	ensure.DeepEqual(t, w.Code, http.StatusOK)
-	ensure.DeepEqual(t, w.Body.String(), "foobar")
+	ensure.DeepEqual(t, w.Body.String(), "foo\n;\nbar")
 	ensure.DeepEqual(t, w.Header(), http.Header{
-		"Content-Length": []string{"6"},
-		"Cache-Control":  []string{cacheControl},
-		"Content-Type":   []string{"application/javascript"},
+		"Content-Length":         []string{"9"},
+		"Cache-Control":          []string{defaultCacheControl},
+		"Content-Type":           []string{"text/javascript; charset=utf-8"},
+		"Etag":                   []string{`"c671d7e742236a0af8c5051df5b45399"`},
+		"X-Content-Type-Options": []string{"nosniff"},
 	})
 }
 
@@ -281,6 +609,19 @@ func TestServeInvalidData(t *testing.T) {
 	ensure.DeepEqual(t, w.Body.String(), http.StatusText(http.StatusBadRequest))
 }
 
+func TestServeNotConfigured(t *testing.T) {
+	h := Handler{Path: "/"}
+	w := httptest.NewRecorder()
+	r := &http.Request{
+		URL: &url.URL{
+			Path: "/W1siZm9vIiwiYmFyIl1d",
+		},
+	}
+	h.ServeHTTP(w, r)
+	ensure.DeepEqual(t, w.Code, http.StatusServiceUnavailable)
+	ensureDisableCaching(t, w.Header())
+}
+
 func TestServeLoadError(t *testing.T) {
 	h := Handler{
 		Path: "/",
@@ -319,123 +660,392 @@ func TestServeHashMismatch(t *testing.T) {
 	ensure.DeepEqual(t, w.Body.String(), http.StatusText(http.StatusNotFound))
 }
 
-func TestLinkStyleInvalidHREF(t *testing.T) {
-	givenErr := errors.New("")
-	ctx := makeCtx(&Handler{
+func TestNoHandlerInContext(t *testing.T) {
+	u, err := URL(context.Background(), "a")
+	ensure.True(t, err == errNoHandlerInContext, err)
+	ensure.DeepEqual(t, u, "")
+}
+
+func TestMultipleHandlers(t *testing.T) {
+	h1 := &Handler{
+		Path: "/a/",
 		Box: funcBox(func(name string) ([]byte, error) {
-			return nil, givenErr
+			return []byte("foo"), nil
+		}),
+	}
+	h2 := &Handler{
+		Path: "/b/",
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("bar"), nil
 		}),
-	})
-	l := LinkStyle{
-		HREF: []string{"foo"},
 	}
-	v, err := l.HTML(ctx)
-	ensure.Nil(t, v)
-	ensure.DeepEqual(t, err, givenErr)
+	u1, err := h1.URL("n")
+	ensure.Nil(t, err)
+	u2, err := h2.URL("n")
+	ensure.Nil(t, err)
+	ensure.NotDeepEqual(t, u1, u2)
+
+	w := httptest.NewRecorder()
+	h1.ServeHTTP(w, &http.Request{URL: &url.URL{Path: u1}})
+	ensure.DeepEqual(t, w.Body.String(), "foo")
+
+	w = httptest.NewRecorder()
+	h2.ServeHTTP(w, &http.Request{URL: &url.URL{Path: u2}})
+	ensure.DeepEqual(t, w.Body.String(), "bar")
+
+	w = httptest.NewRecorder()
+	h2.ServeHTTP(w, &http.Request{URL: &url.URL{Path: u1}})
+	ensure.DeepEqual(t, w.Code, http.StatusNotFound)
 }
 
-func TestLinkStyle(t *testing.T) {
-	ctx := makeCtx(&Handler{
+func TestServeCustomMaxAge(t *testing.T) {
+	h := Handler{
+		Path:   "/",
+		MaxAge: time.Hour,
 		Box: funcBox(func(name string) ([]byte, error) {
 			return []byte("foo"), nil
 		}),
-	})
-	l := LinkStyle{
-		HREF: []string{"foo"},
 	}
-	v, err := l.HTML(ctx)
+	v, err := h.URL("foo")
 	ensure.Nil(t, err)
-	ensure.DeepEqual(t, v, &h.LinkStyle{
-		HREF: "W1siZm9vIiwiYWNiZDE4ZGIiXV0",
-	})
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, &http.Request{URL: &url.URL{Path: v}})
+	ensure.DeepEqual(t, w.Code, http.StatusOK)
+	ensure.DeepEqual(t, w.Header().Get("Cache-Control"), "public, max-age=3600")
 }
 
-func TestScriptInvalidSrc(t *testing.T) {
-	givenErr := errors.New("")
-	ctx := makeCtx(&Handler{
+func TestServePathWithoutTrailingSlash(t *testing.T) {
+	for _, p := range []string{"/assets", "/assets/"} {
+		h := Handler{
+			Path: p,
+			Box: funcBox(func(name string) ([]byte, error) {
+				return []byte("foo"), nil
+			}),
+		}
+		v, err := h.URL("foo")
+		ensure.Nil(t, err)
+		ensure.DeepEqual(t, v, "/assets/W1siZm9vIiwiYWNiZDE4ZGIiXV0")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, &http.Request{URL: &url.URL{Path: v}})
+		ensure.DeepEqual(t, w.Code, http.StatusOK, p)
+		ensure.DeepEqual(t, w.Body.String(), "foo", p)
+	}
+}
+
+func TestServeMux(t *testing.T) {
+	h := &Handler{
+		Path: "/static/",
 		Box: funcBox(func(name string) ([]byte, error) {
-			return nil, givenErr
+			return []byte("foo"), nil
 		}),
-	})
-	l := Script{
-		Src: []string{"foo"},
 	}
-	v, err := l.HTML(ctx)
-	ensure.Nil(t, v)
-	ensure.DeepEqual(t, err, givenErr)
+	mux := http.NewServeMux()
+	mux.Handle(h.Path, h)
+	v, err := h.URL("foo")
+	ensure.Nil(t, err)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", v, nil))
+	ensure.DeepEqual(t, w.Code, http.StatusOK)
+	ensure.DeepEqual(t, w.Body.String(), "foo")
 }
 
-func TestScript(t *testing.T) {
-	ctx := makeCtx(&Handler{
+func TestConcurrentURLAndServe(t *testing.T) {
+	h := &Handler{
+		Path: "/",
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte(name), nil
+		}),
+	}
+	// the results are checked on the test goroutine, as ensure may not fail
+	// the test from others.
+	type result struct {
+		err  error
+		code int
+		body string
+	}
+	results := make([]result, 10)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			v, err := h.URL(strconv.Itoa(i % 3))
+			if err != nil {
+				results[i].err = err
+				return
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, &http.Request{URL: &url.URL{Path: v}})
+			results[i] = result{code: w.Code, body: w.Body.String()}
+		}(i)
+	}
+	wg.Wait()
+	for i, r := range results {
+		ensure.Nil(t, r.err)
+		ensure.DeepEqual(t, r.code, http.StatusOK)
+		ensure.DeepEqual(t, r.body, strconv.Itoa(i%3))
+	}
+}
+
+func TestURLContextCanceled(t *testing.T) {
+	h := &Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			panic("not reached")
+		}),
+	}
+	ctx, cancel := context.WithCancel(makeCtx(h))
+	cancel()
+	u, err := URL(ctx, "a")
+	ensure.True(t, err == context.Canceled, err)
+	ensure.DeepEqual(t, u, "")
+}
+
+func TestClose(t *testing.T) {
+	h := &Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte(name), nil
+		}),
+	}
+	_, err := h.URL("foo")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, h.cache().len(), 1)
+	ensure.Nil(t, h.Close())
+	ensure.DeepEqual(t, h.cache().len(), 0)
+}
+
+func TestMiddleware(t *testing.T) {
+	h := &Handler{
+		Path: "/static/",
 		Box: funcBox(func(name string) ([]byte, error) {
 			return []byte("foo"), nil
 		}),
-	})
-	l := Script{
-		Src:   []string{"foo"},
-		Async: true,
 	}
-	v, err := l.HTML(ctx)
+	m := h.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "next")
+	}))
+	v, err := h.URL("foo")
 	ensure.Nil(t, err)
-	ensure.DeepEqual(t, v, &h.Script{
-		Src:   "W1siZm9vIiwiYWNiZDE4ZGIiXV0",
-		Async: true,
-	})
+
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", v, nil))
+	ensure.DeepEqual(t, w.Body.String(), "foo")
+
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/other", nil))
+	ensure.DeepEqual(t, w.Body.String(), "next")
+}
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	h := &Handler{
+		Path:   "/",
+		Logger: slog.New(slog.NewTextHandler(&buf, nil)),
+		Box:    FSBox(fstest.MapFS{}),
+	}
+	_, err := h.URL("foo.css")
+	ensure.NotNil(t, err)
+	ensure.StringContains(t, buf.String(), "static: load failed")
+	ensure.StringContains(t, buf.String(), "name=foo.css")
+
+	buf.Reset()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, &http.Request{URL: &url.URL{Path: "/bar"}})
+	ensure.DeepEqual(t, w.Code, http.StatusBadRequest)
+	ensure.StringContains(t, buf.String(), "static: bad request")
+	ensure.StringContains(t, buf.String(), "path=/bar")
 }
 
-func TestImgInvalidSrc(t *testing.T) {
-	givenErr := errors.New("")
-	ctx := makeCtx(&Handler{
+func TestQueryVersion(t *testing.T) {
+	h := &Handler{
+		Path:         "/static/",
+		QueryVersion: true,
 		Box: funcBox(func(name string) ([]byte, error) {
-			return nil, givenErr
+			return []byte("foo"), nil
 		}),
-	})
-	l := Img{
-		Src: "foo",
 	}
-	v, err := l.HTML(ctx)
-	ensure.Nil(t, v)
-	ensure.DeepEqual(t, err, givenErr)
+	v, err := h.URL("css/app.css")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, "/static/css/app.css?v=acbd18db")
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", v, nil))
+	ensure.DeepEqual(t, w.Code, http.StatusOK)
+	ensure.DeepEqual(t, w.Body.String(), "foo")
+	ensure.DeepEqual(t, w.Header().Get("Content-Type"), "text/css; charset=utf-8")
+
+	combined, err := h.URL("a.css", "b.css")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, combined, "/static/W1siYS5jc3MiLCJhY2JkMThkYiJdLFsiYi5jc3MiLCJhY2JkMThkYiJdXQ.css")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", combined, nil))
+	ensure.DeepEqual(t, w.Code, http.StatusOK)
+	ensure.DeepEqual(t, w.Body.String(), "foo\nfoo")
 }
 
-func TestImg(t *testing.T) {
-	ctx := makeCtx(&Handler{
+func TestQueryVersionStale(t *testing.T) {
+	h := &Handler{
+		Path: "/static/",
 		Box: funcBox(func(name string) ([]byte, error) {
 			return []byte("foo"), nil
 		}),
-	})
-	l := Img{
-		Src:   "foo",
-		ID:    "a",
-		Class: "b",
-		Style: "c",
-		Alt:   "d",
-	}
-	v, err := l.HTML(ctx)
-	ensure.Nil(t, err)
-	ensure.DeepEqual(t, v, &h.Img{
-		Src:   "W1siZm9vIiwiYWNiZDE4ZGIiXV0",
-		ID:    l.ID,
-		Class: l.Class,
-		Style: l.Style,
-		Alt:   l.Alt,
-	})
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/static/app.css?v=bar", nil))
+	ensure.DeepEqual(t, w.Code, http.StatusNotFound)
 }
 
-func TestInput(t *testing.T) {
-	ctx := makeCtx(&Handler{
+func TestQueryVersionNoName(t *testing.T) {
+	h := &Handler{Path: "/static/"}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/static/?v=bar", nil))
+	ensure.DeepEqual(t, w.Code, http.StatusBadRequest)
+}
+
+func TestURLs(t *testing.T) {
+	h := &Handler{
 		Box: funcBox(func(name string) ([]byte, error) {
 			return []byte("foo"), nil
 		}),
-	})
-	l := Input{Src: "foo"}
-	v, err := l.HTML(ctx)
+	}
+	urls, err := URLs(makeCtx(h), "a", "b", "a")
+	ensure.Nil(t, err)
+	a, err := h.URL("a")
 	ensure.Nil(t, err)
-	ensure.DeepEqual(t, v, &h.Input{Src: "W1siZm9vIiwiYWNiZDE4ZGIiXV0"})
+	b, err := h.URL("b")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, urls, []string{a, b})
 }
 
-func TestNoHandlerInContext(t *testing.T) {
-	u, err := URL(context.Background(), "a")
+func TestURLsZeroNames(t *testing.T) {
+	var h Handler
+	_, err := h.URLs()
+	ensure.True(t, err == errZeroNames, err)
+}
+
+func TestURLsResolver(t *testing.T) {
+	urls, err := URLs(NewContext(context.Background(), urlResolver{}), "a", "b")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, urls, []string{"url", "url"})
+}
+
+func TestURLsNoHandlerInContext(t *testing.T) {
+	_, err := URLs(context.Background(), "a")
 	ensure.True(t, err == errNoHandlerInContext, err)
-	ensure.DeepEqual(t, u, "")
+}
+
+func TestCacheSize(t *testing.T) {
+	var reads int
+	h := &Handler{
+		CacheSize: 4,
+		Box: funcBox(func(name string) ([]byte, error) {
+			reads++
+			return []byte("foo"), nil
+		}),
+	}
+	_, err := h.URL("a")
+	ensure.Nil(t, err)
+	_, err = h.URL("b")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, h.cache().len(), 1)
+	_, err = h.URL("a")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, reads, 3)
+}
+
+func TestPreload(t *testing.T) {
+	var reads int
+	h := &Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			reads++
+			return []byte(name), nil
+		}),
+	}
+	ensure.Nil(t, h.Preload([]string{"a", "b"}, []string{"c"}))
+	ensure.DeepEqual(t, reads, 3)
+	_, err := h.URL("a", "b")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, reads, 3)
+}
+
+func TestPreloadError(t *testing.T) {
+	h := &Handler{Box: FSBox(fstest.MapFS{})}
+	err := h.Preload([]string{"a.css"})
+	ensure.Err(t, err, regexp.MustCompile(`static: preload \[a.css\]: static: asset "a.css" not found`))
+	var notFound *ErrAssetNotFound
+	ensure.True(t, errors.As(err, &notFound))
+}
+
+func TestConcurrentLoadsShareRead(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var reads int32
+	h := &Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			if atomic.AddInt32(&reads, 1) == 1 {
+				close(started)
+			}
+			<-release
+			return []byte("foo"), nil
+		}),
+	}
+	errs := make(chan error, 10)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := h.URL("foo")
+			errs <- err
+		}()
+	}
+	<-started
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		ensure.Nil(t, err)
+	}
+	ensure.DeepEqual(t, atomic.LoadInt32(&reads), int32(1))
+}
+
+func TestRetiredHashes(t *testing.T) {
+	h := Handler{
+		Path:          "/",
+		RetiredHashes: []string{"", "ba"},
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	}
+	// [["foo","bar"]]
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/W1siZm9vIiwiYmFyIl1d", nil))
+	ensure.DeepEqual(t, w.Code, http.StatusGone)
+	ensureDisableCaching(t, w.Header())
+
+	v, err := h.URL("foo")
+	ensure.Nil(t, err)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", v, nil))
+	ensure.DeepEqual(t, w.Code, http.StatusOK)
+}
+
+func TestSeparator(t *testing.T) {
+	h := &Handler{}
+	ensure.DeepEqual(t, string(h.separator("a.js")), "\n;\n")
+	ensure.DeepEqual(t, string(h.separator("a.MJS")), "\n;\n")
+	ensure.DeepEqual(t, string(h.separator("a.css")), "\n")
+	ensure.True(t, h.separator("a.png") == nil)
+}
+
+func TestSeparatorContentLength(t *testing.T) {
+	h := &Handler{
+		Path: "/",
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("// comment"), nil
+		}),
+	}
+	w := serveURL(t, h, nil, "a.js", "b.js")
+	ensure.DeepEqual(t, w.Body.String(), "// comment\n;\n// comment")
+	ensure.DeepEqual(t, w.Header().Get("Content-Length"), "23")
 }
@@ -0,0 +1,80 @@
+package static
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"time"
+)
+
+// readStream fingerprints a file above StreamSize without keeping its
+// contents.
+func (h *Handler) readStream(ob OpenBox, f file, size int64) (file, error) {
+	rc, err := ob.Open(f.Name)
+	if err != nil {
+		h.warn("static: open failed", "name", f.Name, "err", err)
+		if errors.Is(err, fs.ErrNotExist) {
+			return file{}, &ErrAssetNotFound{Name: f.Name, Err: err}
+		}
+		return file{}, err
+	}
+	defer rc.Close()
+	hs := h.newHash()
+	if _, err := io.Copy(hs, rc); err != nil {
+		return file{}, err
+	}
+	f.Hash = h.hashSum(hs)
+	f.Streamed = size
+	return f, nil
+}
+
+// stream copies a streamed file from the Box.
+func (h *Handler) stream(w io.Writer, f file) error {
+	ob, ok := h.Box.(OpenBox)
+	if !ok {
+		return ErrNotConfigured
+	}
+	rc, err := ob.Open(f.Name)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	_, err = io.CopyN(w, rc, f.Streamed)
+	return err
+}
+
+// serveStream serves a single streamed file using http.ServeContent, which
+// handles Range requests, and reports if it did. The Box must open the file
+// as an io.ReadSeeker.
+func (h *Handler) serveStream(w http.ResponseWriter, r *http.Request, f file, contentType string) bool {
+	ob, ok := h.Box.(OpenBox)
+	if !ok {
+		return false
+	}
+	rc, err := ob.Open(f.Name)
+	if err != nil {
+		return false
+	}
+	defer rc.Close()
+	rs, ok := rc.(io.ReadSeeker)
+	if !ok {
+		return false
+	}
+	header := w.Header()
+	h.setCacheControl(header)
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	http.ServeContent(w, r, f.Name, time.Time{}, rs)
+	return true
+}
+
+func streamed(files []file) bool {
+	for _, f := range files {
+		if f.Streamed > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package static
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestStreamLargeFile(t *testing.T) {
+	h := &Handler{
+		Path:       "/",
+		StreamSize: 4,
+		Box: FSBox(fstest.MapFS{
+			"big.txt":   {Data: []byte("0123456789")},
+			"small.txt": {Data: []byte("abc")},
+		}),
+	}
+	v, err := h.URL("big.txt", "small.txt")
+	ensure.Nil(t, err)
+
+	cached, found := h.cache().get("big.txt", 0)
+	ensure.True(t, found)
+	ensure.True(t, cached.Content == nil)
+	ensure.DeepEqual(t, cached.Streamed, int64(10))
+	ensure.DeepEqual(t, cached.Hash, h.hash([]byte("0123456789")))
+	ensure.DeepEqual(t, h.Stats().Bytes, int64(3))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", v, nil))
+	ensure.DeepEqual(t, w.Code, http.StatusOK)
+	ensure.DeepEqual(t, w.Header().Get("Content-Length"), "13")
+	ensure.DeepEqual(t, w.Body.String(), "0123456789abc")
+}
+
+func TestStreamRange(t *testing.T) {
+	h := &Handler{
+		Path:       "/",
+		StreamSize: 4,
+		Box: FSBox(fstest.MapFS{
+			"big.txt": {Data: []byte("0123456789")},
+		}),
+	}
+	v, err := h.URL("big.txt")
+	ensure.Nil(t, err)
+
+	r := httptest.NewRequest("GET", v, nil)
+	r.Header.Set("Range", "bytes=2-4")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	ensure.DeepEqual(t, w.Code, http.StatusPartialContent)
+	ensure.DeepEqual(t, w.Body.String(), "234")
+	ensure.DeepEqual(t, w.Header().Get("Content-Range"), "bytes 2-4/10")
+	ensure.DeepEqual(t, w.Header().Get("Content-Type"), "text/plain; charset=utf-8")
+	ensure.NotDeepEqual(t, w.Header().Get("Cache-Control"), "")
+
+	// a range for an older version of the file is ignored
+	r = httptest.NewRequest("GET", v, nil)
+	r.Header.Set("Range", "bytes=2-4")
+	r.Header.Set("If-Range", `"other"`)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	ensure.DeepEqual(t, w.Code, http.StatusOK)
+	ensure.DeepEqual(t, w.Body.String(), "0123456789")
+}
+
+func TestStreamOpenError(t *testing.T) {
+	givenErr := errors.New("")
+	h := &Handler{StreamSize: 1}
+	_, err := h.readStream(openErrBox{givenErr}, file{Name: "a"}, 2)
+	ensure.DeepEqual(t, err, givenErr)
+	_, err = h.readStream(openErrBox{fs.ErrNotExist}, file{Name: "a"}, 2)
+	ensure.True(t, errors.As(err, new(*ErrAssetNotFound)), err)
+}
+
+// openErrBox is an OpenBox which fails to open files.
+type openErrBox struct {
+	err error
+}
+
+func (b openErrBox) Bytes(name string) ([]byte, error)       { return nil, b.err }
+func (b openErrBox) Stat(name string) (fs.FileInfo, error)   { return nil, b.err }
+func (b openErrBox) Open(name string) (io.ReadCloser, error) { return nil, b.err }
+
+func TestStreamRequiresOpenBox(t *testing.T) {
+	h := &Handler{
+		Path:       "/",
+		StreamSize: 1,
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	}
+	_, err := h.URL("a.txt")
+	ensure.Nil(t, err)
+	cached, found := h.cache().get("a.txt", 0)
+	ensure.True(t, found)
+	ensure.DeepEqual(t, string(cached.Content), "foo")
+}
+
+func TestOverlayBoxOpen(t *testing.T) {
+	b := OverlayBox(
+		funcBox(func(name string) ([]byte, error) {
+			if name == "a" {
+				return []byte("from func"), nil
+			}
+			return nil, fs.ErrNotExist
+		}),
+		FSBox(fstest.MapFS{"b": {Data: []byte("from fs")}}),
+	)
+	for name, expected := range map[string]string{"a": "from func", "b": "from fs"} {
+		rc, err := b.Open(name)
+		ensure.Nil(t, err)
+		contents, err := ioutil.ReadAll(rc)
+		ensure.Nil(t, err)
+		ensure.DeepEqual(t, string(contents), expected)
+		ensure.Nil(t, rc.Close())
+	}
+	_, err := b.Open("c")
+	ensure.True(t, errors.Is(err, fs.ErrNotExist))
+}
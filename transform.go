@@ -0,0 +1,85 @@
+package static
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Minifier minifies contents of the given media type. It matches the Bytes
+// method of the minifier from github.com/tdewolff/minify, which may be used
+// directly.
+type Minifier interface {
+	Bytes(mediatype string, in []byte) ([]byte, error)
+}
+
+// minifyTypes are the media types of files passed to the Minifier.
+var minifyTypes = map[string]string{
+	".css": "text/css",
+	".js":  "text/javascript",
+	".mjs": "text/javascript",
+}
+
+// Transform rewrites the contents of a file, such as to minify it or to inject
+// a build version.
+type Transform func(name string, in []byte) ([]byte, error)
+
+// transform applies the Compiler and built in stylesheet and Web App Manifest
+// rewrites, followed by the Transforms for the extension of the file and then
+// the Minifier. It runs before the contents are hashed or cached. The names of
+// the files referenced by the rewrites are added to deps.
+func (h *Handler) transform(name string, contents []byte, deps *[]string) ([]byte, error) {
+	if c, found := h.Compilers[strings.ToLower(path.Ext(name))]; found {
+		out, err := c.Compile(name, contents)
+		if err != nil {
+			return nil, fmt.Errorf("static: compile %s: %w", name, err)
+		}
+		contents = out
+	}
+	ext := h.outputExt(name)
+	if ext == ".css" {
+		var err error
+		if contents, err = h.transformCSS(name, contents, nil, deps); err != nil {
+			return nil, err
+		}
+	}
+	if ext == ".webmanifest" {
+		var err error
+		if contents, err = h.transformWebManifest(name, contents, deps); err != nil {
+			return nil, fmt.Errorf("static: web manifest %s: %w", name, err)
+		}
+	}
+	for _, t := range h.Transforms[ext] {
+		out, err := t(name, contents)
+		if err != nil {
+			return nil, fmt.Errorf("static: transform %s: %w", name, err)
+		}
+		contents = out
+	}
+	if mediatype, found := minifyTypes[ext]; found && h.Minifier != nil && !h.Dev {
+		out, err := h.Minifier.Bytes(mediatype, contents)
+		if err != nil {
+			return nil, fmt.Errorf("static: minify %s: %w", name, err)
+		}
+		contents = out
+	}
+	return contents, nil
+}
+
+// transformed reports if transform may change the contents of the file, in
+// which case files stored alongside it such as precompressed sidecars no
+// longer match what is served.
+func (h *Handler) transformed(name string) bool {
+	if _, found := h.Compilers[strings.ToLower(path.Ext(name))]; found {
+		return true
+	}
+	ext := h.outputExt(name)
+	if ext == ".css" && (h.RewriteCSSURLs || h.FlattenCSSImports) {
+		return true
+	}
+	if ext == ".webmanifest" || len(h.Transforms[ext]) > 0 {
+		return true
+	}
+	_, found := minifyTypes[ext]
+	return found && h.Minifier != nil && !h.Dev
+}
@@ -0,0 +1,118 @@
+package static
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestTransform(t *testing.T) {
+	h := New(
+		WithBox(funcBox(func(name string) ([]byte, error) {
+			return []byte("v = VERSION"), nil
+		})),
+		WithTransform(".JS", func(name string, in []byte) ([]byte, error) {
+			return bytes.ReplaceAll(in, []byte("VERSION"), []byte("42")), nil
+		}),
+		WithTransform(".js", func(name string, in []byte) ([]byte, error) {
+			return bytes.ReplaceAll(in, []byte(" "), nil), nil
+		}),
+	)
+	v, err := h.ContentContext(makeCtx(h), "a.js")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, string(v), "v=42")
+
+	v, err = h.ContentContext(makeCtx(h), "a.txt")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, string(v), "v = VERSION")
+}
+
+func TestTransformHashed(t *testing.T) {
+	h := &Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("x"), nil
+		}),
+		Transforms: map[string][]Transform{
+			".js": {func(name string, in []byte) ([]byte, error) {
+				return []byte("foo"), nil
+			}},
+		},
+	}
+	v, err := h.URL("a.js")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, "W1siYS5qcyIsImFjYmQxOGRiIl1d.js")
+}
+
+func TestTransformError(t *testing.T) {
+	givenErr := errors.New("boom")
+	h := New(
+		WithBox(funcBox(func(name string) ([]byte, error) {
+			return []byte("x"), nil
+		})),
+		WithTransform(".js", func(name string, in []byte) ([]byte, error) {
+			return nil, givenErr
+		}),
+	)
+	_, err := h.URL("a.js")
+	ensure.True(t, errors.Is(err, givenErr), err)
+	ensure.DeepEqual(t, err.Error(), "static: transform a.js: boom")
+}
+
+type spaceMinifier struct{ types []string }
+
+func (m *spaceMinifier) Bytes(mediatype string, in []byte) ([]byte, error) {
+	m.types = append(m.types, mediatype)
+	if mediatype == "text/css" && bytes.Contains(in, []byte("{{")) {
+		return nil, errors.New("bad css")
+	}
+	return bytes.ReplaceAll(in, []byte(" "), nil), nil
+}
+
+func TestMinifier(t *testing.T) {
+	m := &spaceMinifier{}
+	h := New(
+		WithBox(funcBox(func(name string) ([]byte, error) {
+			return []byte("a { }"), nil
+		})),
+		WithTransform(".css", func(name string, in []byte) ([]byte, error) {
+			return append(in, " b { }"...), nil
+		}),
+		WithMinifier(m),
+	)
+	for _, name := range []string{"a.css", "a.js", "a.txt"} {
+		v, err := h.ContentContext(makeCtx(h), name)
+		ensure.Nil(t, err)
+		if name == "a.txt" {
+			ensure.DeepEqual(t, string(v), "a { }")
+		} else if name == "a.css" {
+			ensure.DeepEqual(t, string(v), "a{}b{}")
+		}
+	}
+	ensure.DeepEqual(t, m.types, []string{"text/css", "text/javascript"})
+}
+
+func TestMinifierDev(t *testing.T) {
+	h := &Handler{
+		Dev:      true,
+		Minifier: &spaceMinifier{},
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("a { }"), nil
+		}),
+	}
+	v, err := h.ContentContext(makeCtx(h), "a.css")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, string(v), "a { }")
+}
+
+func TestMinifierError(t *testing.T) {
+	h := &Handler{
+		Minifier: &spaceMinifier{},
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("{{"), nil
+		}),
+	}
+	_, err := h.URL("a.css")
+	ensure.DeepEqual(t, err.Error(), "static: minify a.css: bad css")
+}
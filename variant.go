@@ -0,0 +1,106 @@
+package static
+
+import (
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// imageVariant returns the resizer for the given width, or the accepted image
+// format for the files, if any, and reports if the response depends on the
+// Accept header.
+func (h *Handler) imageVariant(r *http.Request, files []file, width int) (Compiler, bool) {
+	if len(files) != 1 || !resizable(files[0].Name) {
+		return nil, false
+	}
+	if width > 0 {
+		return &resizer{width: width, contentType: h.typeByExtension(path.Ext(files[0].Name))}, false
+	}
+	if len(h.ImageFormats) == 0 {
+		return nil, false
+	}
+	accept := r.Header.Get("Accept")
+	for _, format := range h.ImageFormats {
+		if acceptsType(accept, format.ContentType()) {
+			return format, true
+		}
+	}
+	return nil, true
+}
+
+// acceptsType reports if the Accept header explicitly lists the media type,
+// as wildcards do not indicate support for newer image formats.
+func acceptsType(accept, contentType string) bool {
+	want, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, part := range strings.Split(accept, ",") {
+		mediatype, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err == nil && mediatype == want && params["q"] != "0" {
+			return true
+		}
+	}
+	return false
+}
+
+// variantName identifies the converted image, such as "webp" or "w640".
+func variantName(format Compiler) string {
+	if r, ok := format.(*resizer); ok {
+		return "w" + strconv.Itoa(r.width)
+	}
+	mediatype, _, _ := mime.ParseMediaType(format.ContentType())
+	return path.Base(mediatype)
+}
+
+// variantETag distinguishes the entity tag of a converted image.
+func variantETag(tag string, format Compiler) string {
+	return suffixETag(tag, variantName(format))
+}
+
+// convert returns the contents of the file converted to the format,
+// converting it on first use. Concurrent conversions of the same image share
+// a single call, and failures are remembered until the cache is cleared.
+// Converted images are bounded by CacheSize and CacheTTL like other files.
+func (h *Handler) convert(f file, format Compiler) ([]byte, bool) {
+	if f.Content == nil || f.Streamed > 0 {
+		return nil, false
+	}
+	key := f.Name + "\x00" + f.Hash + "\x00" + variantName(format)
+	if v, found := h.variants.get(key, h.CacheTTL); found {
+		return v.Content, v.Content != nil
+	}
+	v, _, _ := h.loads.Do(key, func() (interface{}, error) {
+		if v, found := h.variants.get(key, h.CacheTTL); found {
+			return v, nil
+		}
+		converted, err := format.Compile(f.Name, f.Content)
+		if err != nil {
+			h.warn("static: image conversion failed", "name", f.Name, "type", format.ContentType(), "err", err)
+			converted = nil
+		}
+		v := file{Name: key, Content: converted}
+		h.variants.add(v, h.CacheSize, h.CacheTTL)
+		return v, nil
+	})
+	converted := v.(file).Content
+	return converted, converted != nil
+}
+
+// serveVariant serves the converted image, along with its entity tag. It
+// reports false if the image cannot be converted, so the original is served
+// instead.
+func (h *Handler) serveVariant(w http.ResponseWriter, r *http.Request, files []file, format Compiler) bool {
+	contents, ok := h.convert(files[0], format)
+	if !ok {
+		return false
+	}
+	if h.notModified(w, r, variantETag(etag(files), format), nil) {
+		return true
+	}
+	h.writeHeaders(w, int64(len(contents)), format.ContentType())
+	w.Write(contents)
+	return true
+}
@@ -0,0 +1,157 @@
+package static
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/facebookgo/ensure"
+)
+
+type fakeFormat struct {
+	contentType string
+	calls       int
+	err         error
+}
+
+func (f *fakeFormat) Compile(name string, in []byte) ([]byte, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return append([]byte(f.contentType+":"), in...), nil
+}
+
+func (f *fakeFormat) ContentType() string {
+	return f.contentType
+}
+
+func TestImageFormats(t *testing.T) {
+	avif := &fakeFormat{contentType: "image/avif"}
+	webp := &fakeFormat{contentType: "image/webp"}
+	h := &Handler{
+		Path:         "/",
+		ImageFormats: []Compiler{avif, webp},
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	}
+
+	w := serveURL(t, h, http.Header{"Accept": {"image/webp,*/*"}}, "a.png")
+	ensure.DeepEqual(t, w.Body.String(), "image/webp:foo")
+	ensure.DeepEqual(t, w.Header().Get("Content-Type"), "image/webp")
+	ensure.DeepEqual(t, w.Header().Get("Vary"), "Accept")
+	webpTag := w.Header().Get("ETag")
+
+	w = serveURL(t, h, http.Header{"Accept": {"image/webp"}, "If-None-Match": {webpTag}}, "a.png")
+	ensure.DeepEqual(t, w.Code, http.StatusNotModified)
+	ensure.DeepEqual(t, w.Header().Get("Vary"), "Accept")
+
+	w = serveURL(t, h, http.Header{"Accept": {"image/avif,image/webp"}}, "a.png")
+	ensure.DeepEqual(t, w.Body.String(), "image/avif:foo")
+	ensure.NotDeepEqual(t, w.Header().Get("ETag"), webpTag)
+
+	w = serveURL(t, h, http.Header{"Accept": {"image/avif;q=0,*/*"}}, "a.jpg")
+	ensure.DeepEqual(t, w.Body.String(), "foo")
+	ensure.DeepEqual(t, w.Header().Get("Content-Type"), "image/jpeg")
+	ensure.DeepEqual(t, w.Header().Get("Vary"), "Accept")
+
+	w = serveURL(t, h, http.Header{"Accept": {"image/webp"}}, "a.png")
+	ensure.DeepEqual(t, w.Body.String(), "image/webp:foo")
+	ensure.DeepEqual(t, webp.calls, 1)
+
+	w = serveURL(t, h, http.Header{"Accept": {"image/webp"}}, "a.gif")
+	ensure.DeepEqual(t, w.Body.String(), "foo")
+	ensure.DeepEqual(t, w.Header().Get("Vary"), "")
+}
+
+func TestImageFormatsConversionError(t *testing.T) {
+	h := &Handler{
+		Path:         "/",
+		ImageFormats: []Compiler{&fakeFormat{contentType: "image/webp", err: errors.New("")}},
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	}
+	w := serveURL(t, h, http.Header{"Accept": {"image/webp"}}, "a.png")
+	ensure.DeepEqual(t, w.Body.String(), "foo")
+	ensure.DeepEqual(t, w.Header().Get("Content-Type"), "image/png")
+	ensure.DeepEqual(t, w.Header().Get("Vary"), "Accept")
+	ensure.DeepEqual(t, w.Header().Get("ETag"), etag([]file{{Name: "a.png", Hash: "acbd18db"}}))
+}
+
+func TestImageFormatsStreamed(t *testing.T) {
+	webp := &fakeFormat{contentType: "image/webp"}
+	h := &Handler{
+		Path:         "/",
+		StreamSize:   1,
+		ImageFormats: []Compiler{webp},
+		Box: FSBox(fstest.MapFS{
+			"a.png": {Data: []byte("foo")},
+		}),
+	}
+	w := serveURL(t, h, http.Header{"Accept": {"image/webp"}}, "a.png")
+	ensure.DeepEqual(t, w.Body.String(), "foo")
+	ensure.DeepEqual(t, w.Header().Get("Content-Type"), "image/png")
+	ensure.StringDoesNotContain(t, w.Header().Get("ETag"), "webp")
+	ensure.DeepEqual(t, w.Header().Get("Vary"), "Accept")
+	ensure.DeepEqual(t, webp.calls, 0)
+}
+
+func TestImageFormatsConcurrent(t *testing.T) {
+	webp := &fakeFormat{contentType: "image/webp"}
+	h := &Handler{
+		Path:         "/",
+		ImageFormats: []Compiler{webp},
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("foo"), nil
+		}),
+	}
+	u, err := h.URL("a.png")
+	ensure.Nil(t, err)
+	bodies := make(chan string, 10)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := httptest.NewRequest("GET", u, nil)
+			r.Header.Set("Accept", "image/webp")
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			bodies <- w.Body.String()
+		}()
+	}
+	wg.Wait()
+	close(bodies)
+	for body := range bodies {
+		ensure.DeepEqual(t, body, "image/webp:foo")
+	}
+	ensure.DeepEqual(t, webp.calls, 1)
+}
+
+func TestImageFormatsCacheSize(t *testing.T) {
+	h := &Handler{
+		Path:         "/",
+		CacheSize:    1,
+		ImageFormats: []Compiler{&fakeFormat{contentType: "image/webp"}},
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte(name), nil
+		}),
+	}
+	for _, name := range []string{"a.png", "b.png", "c.png"} {
+		w := serveURL(t, h, http.Header{"Accept": {"image/webp"}}, name)
+		ensure.DeepEqual(t, w.Body.String(), "image/webp:"+name)
+	}
+	ensure.DeepEqual(t, h.variants.len(), 1)
+}
+
+func TestAcceptsType(t *testing.T) {
+	ensure.True(t, acceptsType("image/avif,image/webp,*/*;q=0.8", "image/webp"))
+	ensure.False(t, acceptsType("*/*", "image/webp"))
+	ensure.False(t, acceptsType("image/webp;q=0", "image/webp"))
+	ensure.False(t, acceptsType("image/webp", ""))
+}
@@ -0,0 +1,65 @@
+package static
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watch watches the directory tree at root, which should be where the Box
+// loads files from, and drops cached files whenever anything in it changes,
+// along with failures once files are created or renamed.
+// The watcher is stopped by Close.
+func (h *Handler) Watch(root string) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+		return w.Add(p)
+	})
+	if err != nil {
+		w.Close()
+		return err
+	}
+
+	h.mu.Lock()
+	h.watchers = append(h.watchers, w)
+	h.mu.Unlock()
+	go h.watch(w)
+	return nil
+}
+
+func (h *Handler) watch(w *fsnotify.Watcher) {
+	for {
+		select {
+		case ev, ok := <-w.Events:
+			if !ok {
+				return
+			}
+			if ev.Op&fsnotify.Create != 0 {
+				if fi, err := os.Stat(ev.Name); err == nil && fi.IsDir() {
+					if err := w.Add(ev.Name); err != nil {
+						h.warn("static: watch failed", "name", ev.Name, "err", err)
+					}
+				}
+			}
+			h.debug("static: file changed", "name", ev.Name)
+			h.cache().clear()
+			// requests for files which were missing may now succeed
+			if ev.Op&(fsnotify.Create|fsnotify.Rename) != 0 {
+				h.negative.clear()
+			}
+		case err, ok := <-w.Errors:
+			if !ok {
+				return
+			}
+			h.warn("static: watch failed", "err", err)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package static
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "foo.css")
+	ensure.Nil(t, os.WriteFile(name, []byte("foo"), 0644))
+
+	h := &Handler{Box: FSBox(os.DirFS(dir))}
+	defer h.Close()
+	v1, err := h.URL("foo.css")
+	ensure.Nil(t, err)
+	ensure.Nil(t, h.Watch(dir))
+
+	ensure.Nil(t, os.WriteFile(name, []byte("bar"), 0644))
+	ensure.Nil(t, os.Chtimes(name, time.Now(), time.Now().Add(time.Hour)))
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		v2, err := h.URL("foo.css")
+		ensure.Nil(t, err)
+		if v2 != v1 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("cache was not invalidated")
+}
+
+func TestWatchCreateClearsNegative(t *testing.T) {
+	dir := t.TempDir()
+	h := &Handler{
+		Path:             "/",
+		NegativeCacheTTL: time.Hour,
+		Box:              FSBox(os.DirFS(dir)),
+	}
+	defer h.Close()
+	ensure.Nil(t, h.Watch(dir))
+	u, err := (&Handler{Path: "/", Box: funcBox(func(name string) ([]byte, error) {
+		return []byte("foo"), nil
+	})}).URL("foo.css")
+	ensure.Nil(t, err)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", u, nil))
+	ensure.DeepEqual(t, w.Code, http.StatusNotFound)
+
+	ensure.Nil(t, os.WriteFile(filepath.Join(dir, "foo.css"), []byte("foo"), 0644))
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", u, nil))
+		if w.Code == http.StatusOK {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("negative cache was not invalidated")
+}
+
+func TestWatchMissingDir(t *testing.T) {
+	var h Handler
+	ensure.NotNil(t, h.Watch(filepath.Join(t.TempDir(), "missing")))
+	ensure.Nil(t, h.Close())
+}
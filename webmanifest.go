@@ -0,0 +1,60 @@
+package static
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+)
+
+// transformWebManifest replaces the src of the icons, screenshots and shortcut
+// icons in a Web App Manifest with hashed URLs. Sources are relative to the
+// manifest, and other sources such as absolute URLs are left as is. The
+// referenced names are added to deps, as the manifest changes along with them.
+func (h *Handler) transformWebManifest(name string, contents []byte, deps *[]string) ([]byte, error) {
+	var manifest map[string]interface{}
+	if err := json.Unmarshal(contents, &manifest); err != nil {
+		return nil, err
+	}
+	if err := h.rewriteImages(name, manifest["icons"], deps); err != nil {
+		return nil, err
+	}
+	if err := h.rewriteImages(name, manifest["screenshots"], deps); err != nil {
+		return nil, err
+	}
+	if shortcuts, ok := manifest["shortcuts"].([]interface{}); ok {
+		for _, s := range shortcuts {
+			if shortcut, ok := s.(map[string]interface{}); ok {
+				if err := h.rewriteImages(name, shortcut["icons"], deps); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return json.Marshal(manifest)
+}
+
+// rewriteImages replaces the src of a list of image resources.
+func (h *Handler) rewriteImages(name string, v interface{}, deps *[]string) error {
+	images, _ := v.([]interface{})
+	for _, i := range images {
+		image, ok := i.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		src, ok := image["src"].(string)
+		if !ok || !relativeURL(src) {
+			continue
+		}
+		target := path.Join(path.Dir(name), src)
+		if validName(target) != nil || path.Ext(target) == ".webmanifest" {
+			continue
+		}
+		*deps = append(*deps, target)
+		u, err := h.URLContext(context.Background(), target)
+		if err != nil {
+			return err
+		}
+		image["src"] = u
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package static
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+)
+
+const testWebManifest = `{
+	"name": "App",
+	"icons": [
+		{"src": "icon-192.png", "sizes": "192x192"},
+		{"src": "https://cdn.example.com/icon.png"},
+		{"sizes": "1x1"}
+	],
+	"shortcuts": [{"name": "New", "icons": [{"src": "../img/new.png"}]}]
+}`
+
+func TestWebManifest(t *testing.T) {
+	var reads int
+	h := &Handler{
+		Path: "/static/",
+		Box: funcBox(func(name string) ([]byte, error) {
+			if name == "app/manifest.webmanifest" {
+				reads++
+				return []byte(testWebManifest), nil
+			}
+			return []byte("foo"), nil
+		}),
+	}
+	w := serveURL(t, h, nil, "app/manifest.webmanifest")
+	ensure.DeepEqual(t, w.Code, http.StatusOK)
+	ensure.DeepEqual(t, w.Header().Get("Content-Type"), "application/manifest+json")
+	var manifest struct {
+		Name  string
+		Icons []struct {
+			Src   string
+			Sizes string
+		}
+		Shortcuts []struct {
+			Icons []struct{ Src string }
+		}
+	}
+	ensure.Nil(t, json.Unmarshal(w.Body.Bytes(), &manifest))
+	icon, err := h.URL("app/icon-192.png")
+	ensure.Nil(t, err)
+	shortcut, err := h.URL("img/new.png")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, manifest.Name, "App")
+	ensure.DeepEqual(t, manifest.Icons[0].Src, icon)
+	ensure.DeepEqual(t, manifest.Icons[0].Sizes, "192x192")
+	ensure.DeepEqual(t, manifest.Icons[1].Src, "https://cdn.example.com/icon.png")
+	ensure.DeepEqual(t, manifest.Icons[2].Src, "")
+	ensure.DeepEqual(t, manifest.Shortcuts[0].Icons[0].Src, shortcut)
+
+	w = serveURL(t, h, http.Header{"If-None-Match": {w.Header().Get("ETag")}}, "app/manifest.webmanifest")
+	ensure.DeepEqual(t, w.Code, http.StatusNotModified)
+	ensure.DeepEqual(t, reads, 1)
+}
+
+func TestWebManifestMissingIcon(t *testing.T) {
+	h := &Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			if name == "manifest.webmanifest" {
+				return []byte(testWebManifest), nil
+			}
+			return nil, &ErrAssetNotFound{Name: name}
+		}),
+	}
+	_, err := h.URL("manifest.webmanifest")
+	ensure.Err(t, err, regexp.MustCompile(`web manifest manifest.webmanifest: .*icon-192.png`))
+}
+
+func TestWebManifestInvalid(t *testing.T) {
+	h := &Handler{
+		Box: funcBox(func(name string) ([]byte, error) {
+			return []byte("{"), nil
+		}),
+	}
+	_, err := h.URL("manifest.webmanifest")
+	ensure.NotNil(t, err)
+}